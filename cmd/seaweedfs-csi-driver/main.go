@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/datalocality"
 	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/driver"
@@ -17,21 +19,29 @@ var (
 	enableAttacher = flag.Bool("attacher", true, "enable attacher, by default enabled for backward compatibility")
 	driverName     = flag.String("driverName", "seaweedfs-csi-driver", "CSI driver name, used by CSIDriver and StorageClass")
 
-	filer             = flag.String("filer", "localhost:8888", "filer server")
-	endpoint          = flag.String("endpoint", "unix://tmp/seaweedfs-csi.sock", "CSI endpoint to accept gRPC calls")
-	mountEndpoint     = flag.String("mountEndpoint", "unix:///tmp/seaweedfs-mount.sock", "mount service endpoint")
-	nodeID            = flag.String("nodeid", "", "node id")
-	version           = flag.Bool("version", false, "Print the version and exit.")
-	concurrentWriters = flag.Int("concurrentWriters", 128, "limit concurrent goroutine writers if not 0")
-	concurrentReaders = flag.Int("concurrentReaders", 128, "limit concurrent chunk fetches for read operations")
-	cacheCapacityMB   = flag.Int("cacheCapacityMB", 0, "local file chunk cache capacity in MB")
-	cacheMetaTtlSec   = flag.Int("cacheMetaTtlSec", 60, "metadata cache TTL in seconds")
-	cacheDir          = flag.String("cacheDir", os.TempDir(), "local cache directory for file chunks and meta data")
-	uidMap            = flag.String("map.uid", "", "map local uid to uid on filer, comma-separated <local_uid>:<filer_uid>")
-	gidMap            = flag.String("map.gid", "", "map local gid to gid on filer, comma-separated <local_gid>:<filer_gid>")
-	dataCenter        = flag.String("dataCenter", "", "dataCenter this node is running in (locality-definition)")
-	dataLocalityStr   = flag.String("dataLocality", "", "which volume-nodes pods will use for activity (one-of: 'write_preferLocalDc'). Requires used locality-definitions to be set")
-	dataLocality      datalocality.DataLocality
+	filer                = flag.String("filer", "localhost:8888", "filer server")
+	endpoint             = flag.String("endpoint", "unix://tmp/seaweedfs-csi.sock", "CSI endpoint to accept gRPC calls")
+	mountEndpoint        = flag.String("mountEndpoint", "unix:///tmp/seaweedfs-mount.sock", "mount service endpoint")
+	nodeID               = flag.String("nodeid", "", "node id")
+	version              = flag.Bool("version", false, "Print the version and exit.")
+	concurrentWriters    = flag.Int("concurrentWriters", 128, "limit concurrent goroutine writers if not 0")
+	concurrentReaders    = flag.Int("concurrentReaders", 128, "limit concurrent chunk fetches for read operations")
+	cacheCapacityMB      = flag.Int("cacheCapacityMB", 0, "local file chunk cache capacity in MB")
+	cacheMetaTtlSec      = flag.Int("cacheMetaTtlSec", 60, "metadata cache TTL in seconds")
+	cacheDir             = flag.String("cacheDir", os.TempDir(), "local cache directory for file chunks and meta data")
+	cacheRoots           = flag.String("cacheRoots", "", "comma-separated alternate cache directories volumes may select with the cacheDir parameter")
+	maxCacheCapacityMB   = flag.Int("maxCacheCapacityMB", 0, "maximum per-volume cache capacity in MB, 0 for no maximum")
+	maxConcurrentReaders = flag.Int("maxConcurrentReaders", 0, "maximum per-volume concurrentReaders, 0 for no maximum")
+	maxConcurrentWriters = flag.Int("maxConcurrentWriters", 0, "maximum per-volume concurrentWriters, 0 for no maximum")
+	uidMap               = flag.String("map.uid", "", "map local uid to uid on filer, comma-separated <local_uid>:<filer_uid>")
+	gidMap               = flag.String("map.gid", "", "map local gid to gid on filer, comma-separated <local_gid>:<filer_gid>")
+	dataCenter           = flag.String("dataCenter", "", "dataCenter this node is running in (locality-definition)")
+	rack                 = flag.String("rack", "", "rack this node is running in within its dataCenter (locality-definition)")
+	dataLocalityStr      = flag.String("dataLocality", "", "which volume-nodes pods will use for activity (one-of: 'write_preferLocalDc', 'read_preferLocalDc'). Requires used locality-definitions to be set")
+	volumeParentDirs     = flag.String("volumeParentDirs", "", "comma-separated filer directories volumes are created in, listed by ListVolumes, by default the filer's buckets directory")
+	trashDir             = flag.String("trashDir", "/.csi-trash", "filer directory volumes with deletePolicy=trash are moved to on deletion")
+	trashRetention       = flag.Duration("trashRetention", 7*24*time.Hour, "how long deleted volumes are kept in trashDir before the controller purges them, 0 keeps them forever")
+	dataLocality         datalocality.DataLocality
 )
 
 func main() {
@@ -91,10 +101,27 @@ func main() {
 	drv.CacheCapacityMB = *cacheCapacityMB
 	drv.CacheMetaTtlSec = *cacheMetaTtlSec
 	drv.CacheDir = *cacheDir
+	for _, dir := range strings.Split(*cacheRoots, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			drv.CacheRoots = append(drv.CacheRoots, path.Clean(dir))
+		}
+	}
+	drv.MaxCacheCapacityMB = *maxCacheCapacityMB
+	drv.MaxConcurrentReaders = *maxConcurrentReaders
+	drv.MaxConcurrentWriters = *maxConcurrentWriters
 	drv.UidMap = *uidMap
 	drv.GidMap = *gidMap
 	drv.DataCenter = *dataCenter
+	drv.Rack = *rack
 	drv.DataLocality = dataLocality
+	for _, dir := range strings.Split(*volumeParentDirs, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			drv.VolumeParentDirs = append(drv.VolumeParentDirs, path.Clean(dir))
+		}
+	}
+
+	drv.TrashDir = path.Clean(*trashDir)
+	drv.TrashRetention = *trashRetention
 
 	drv.Run()
 }
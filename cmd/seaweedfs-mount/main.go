@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -17,8 +18,13 @@ import (
 )
 
 var (
-	endpoint   = flag.String("endpoint", "unix:///tmp/seaweedfs-mount.sock", "endpoint the mount service listens on")
-	weedBinary = flag.String("weedBinary", mountmanager.DefaultWeedBinary, "path to the weed binary")
+	endpoint       = flag.String("endpoint", "unix:///tmp/seaweedfs-mount.sock", "endpoint the mount service listens on")
+	weedBinary     = flag.String("weedBinary", mountmanager.DefaultWeedBinary, "path to the weed binary")
+	stateFile      = flag.String("stateFile", "", "file the running mounts are journaled to, so that a restarted service re-adopts them; defaults to mounts.json next to the endpoint socket")
+	restartCrashed = flag.Bool("restartCrashed", false, "restart weed mount processes that exit without being unmounted")
+	maxRestarts    = flag.Int("maxRestarts", mountmanager.DefaultMaxRestarts, "how many quick crashes in a row of a weed mount process are restarted before the mount is given up")
+	cgroups        = flag.Bool("cgroups", false, "run every weed mount process in its own cgroup v2 sub-group, enforcing the memory and CPU limits of its volume")
+	hostScopes     = flag.Bool("hostScopes", false, "run every weed mount process in a systemd scope of the host, so that it outlives the mount service container and is re-adopted after a restart; needs hostPID")
 )
 
 func main() {
@@ -45,18 +51,56 @@ func main() {
 		_ = os.Remove(address)
 	}()
 
-	manager := mountmanager.NewManager(mountmanager.Config{WeedBinary: *weedBinary})
+	if *stateFile == "" {
+		*stateFile = filepath.Join(filepath.Dir(address), "mounts.json")
+	}
+	manager := mountmanager.NewManager(mountmanager.Config{
+		WeedBinary:     *weedBinary,
+		StateFile:      *stateFile,
+		RestartCrashed: *restartCrashed,
+		MaxRestarts:    *maxRestarts,
+		Cgroups:        *cgroups,
+		HostScopes:     *hostScopes,
+	})
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/mount", makePostHandler(manager.Mount))
 	mux.HandleFunc("/unmount", makePostHandler(manager.Unmount))
+	mux.HandleFunc("/mounts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, mountmanager.ListMountsResponse{Mounts: manager.List()})
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		volumeID := r.URL.Query().Get("volumeId")
+		if volumeID == "" {
+			writeError(w, http.StatusBadRequest, "volumeId is required")
+			return
+		}
+		status, err := manager.Status(volumeID)
+		if errors.Is(err, mountmanager.ErrVolumeNotMounted) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	})
 
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
 
-	server := &http.Server{Handler: mux}
+	server := mountmanager.NewServer(manager, mux)
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
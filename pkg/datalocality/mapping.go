@@ -9,12 +9,14 @@ type DataLocality uint
 const (
 	None				DataLocality = iota
 	Write_preferLocalDc
+	Read_preferLocalDc
 )
 
 // DataLocality -> String
 var dataLocalityStringMap = []string {
 	"none",
 	"write_preferlocaldc",
+	"read_preferlocaldc",
 }
 func (d DataLocality) String() string {
 	return dataLocalityStringMap[d]
@@ -24,6 +26,7 @@ func (d DataLocality) String() string {
 var stringDataLocalityMap = map[string]DataLocality {
 	"none": None,
 	"write_preferlocaldc": Write_preferLocalDc,
+	"read_preferlocaldc": Read_preferLocalDc,
 }
 func FromString(s string) (DataLocality, bool) {
 	value, ok := stringDataLocalityMap[strings.ToLower(s)]
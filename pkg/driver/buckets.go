@@ -0,0 +1,54 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// filerBucketsDir returns the directory the filers of the cluster keep S3
+// buckets in, as reported by their configuration. The answer is cached for
+// the lifetime of the driver; failures are not, so that the next call asks
+// again.
+func (d *SeaweedFsDriver) filerBucketsDir(ctx context.Context) (string, error) {
+	d.bucketsDirMutex.Lock()
+	defer d.bucketsDirMutex.Unlock()
+	if d.dirBuckets != "" {
+		return d.dirBuckets, nil
+	}
+
+	var dirBuckets string
+	err := d.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		resp, err := client.GetFilerConfiguration(ctx, &filer_pb.GetFilerConfigurationRequest{})
+		if err != nil {
+			return err
+		}
+		dirBuckets = resp.DirBuckets
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("get filer configuration: %v", err)
+	}
+	if dirBuckets == "" {
+		return "", fmt.Errorf("filer reported no buckets directory")
+	}
+	d.dirBuckets = path.Clean(dirBuckets)
+	glog.V(4).Infof("filers %v keep buckets in %s", d.filers, d.dirBuckets)
+	return d.dirBuckets, nil
+}
+
+// bucketsDir is filerBucketsDir for RPCs, which fail with Unavailable while
+// the filer cannot be asked: a guessed directory would resolve legacy volume
+// IDs to directories that are not theirs.
+func (d *SeaweedFsDriver) bucketsDir(ctx context.Context) (string, error) {
+	dir, err := d.filerBucketsDir(ctx)
+	if err != nil {
+		return "", status.Errorf(codes.Unavailable, "error getting buckets directory: %v", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSplitVolumeIdUsesFilerBucketsDir(t *testing.T) {
+	d := &SeaweedFsDriver{dirBuckets: "/s3"}
+	tests := map[string][2]string{
+		"pvc-1":                             {"/s3", "pvc-1"},
+		"/buckets/pvc-1":                    {"/buckets", "pvc-1"},
+		"seaweedfs://filer-b:8888/s3/pvc-1": {"/s3", "pvc-1"},
+	}
+	for id, want := range tests {
+		if parentDir, volumeName, err := d.splitVolumeId(context.Background(), id); err != nil || parentDir != want[0] || volumeName != want[1] {
+			t.Errorf("splitVolumeId(%q) = %q, %q, %v, want %q, %q", id, parentDir, volumeName, err, want[0], want[1])
+		}
+	}
+}
+
+// TestLegacyVolumeIdNeedsBucketsDir verifies that legacy volume IDs are not
+// resolved against a guessed buckets directory while the filer cannot be
+// asked, while full paths need no filer.
+func TestLegacyVolumeIdNeedsBucketsDir(t *testing.T) {
+	d := &SeaweedFsDriver{}
+	if _, err := d.bucketsDir(context.Background()); status.Code(err) != codes.Unavailable {
+		t.Fatalf("bucketsDir() without a filer = %v, want Unavailable", err)
+	}
+	if _, _, err := d.splitVolumeId(context.Background(), "pvc-1"); status.Code(err) != codes.Unavailable {
+		t.Fatalf("splitVolumeId of a legacy ID without a filer = %v, want Unavailable", err)
+	}
+	if parentDir, volumeName, err := d.splitVolumeId(context.Background(), "/buckets/pvc-1"); err != nil || parentDir != "/buckets" || volumeName != "pvc-1" {
+		t.Fatalf("splitVolumeId of a full path = %q, %q, %v", parentDir, volumeName, err)
+	}
+	if d.dirBuckets != "" {
+		t.Fatalf("the failure must not be cached")
+	}
+}
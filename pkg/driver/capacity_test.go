@@ -3,18 +3,20 @@ package driver
 import (
 	"errors"
 	"testing"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
 )
 
 func TestResolveVolumeCapacityUsesVolumeContextFallback(t *testing.T) {
 	ns := &NodeServer{
-		capacityFn: func(volumeID string) (int64, error) {
+		capacityFn: func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
 			return 0, errors.New("Kubernetes API unavailable")
 		},
 	}
 
 	got, ok, err := ns.resolveVolumeCapacity("/buckets/pvc-1234", map[string]string{
 		volumeCapacityKey: "5368709120",
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("resolveVolumeCapacity: %v", err)
 	}
@@ -28,14 +30,14 @@ func TestResolveVolumeCapacityUsesVolumeContextFallback(t *testing.T) {
 
 func TestResolveVolumeCapacityPrefersOrchestratorValue(t *testing.T) {
 	ns := &NodeServer{
-		capacityFn: func(volumeID string) (int64, error) {
+		capacityFn: func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
 			return 10 * 1024 * 1024 * 1024, nil
 		},
 	}
 
 	got, ok, err := ns.resolveVolumeCapacity("pvc-1234", map[string]string{
 		volumeCapacityKey: "5368709120",
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("resolveVolumeCapacity: %v", err)
 	}
@@ -49,26 +51,26 @@ func TestResolveVolumeCapacityPrefersOrchestratorValue(t *testing.T) {
 
 func TestResolveVolumeCapacityRejectsInvalidContextValue(t *testing.T) {
 	ns := &NodeServer{
-		capacityFn: func(volumeID string) (int64, error) {
+		capacityFn: func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
 			return 0, errors.New("Kubernetes API unavailable")
 		},
 	}
 
 	if _, _, err := ns.resolveVolumeCapacity("pvc-1234", map[string]string{
 		volumeCapacityKey: "not-a-size",
-	}); err == nil {
+	}, nil); err == nil {
 		t.Fatal("expected invalid volume capacity to return an error")
 	}
 }
 
 func TestResolveVolumeCapacityAllowsUnavailableCapacity(t *testing.T) {
 	ns := &NodeServer{
-		capacityFn: func(volumeID string) (int64, error) {
+		capacityFn: func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
 			return 0, errors.New("Kubernetes API unavailable")
 		},
 	}
 
-	_, ok, err := ns.resolveVolumeCapacity("pvc-1234", nil)
+	_, ok, err := ns.resolveVolumeCapacity("pvc-1234", nil, nil)
 	if err != nil {
 		t.Fatalf("resolveVolumeCapacity: %v", err)
 	}
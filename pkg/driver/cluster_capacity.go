@@ -0,0 +1,123 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+)
+
+// capacityFilter selects the part of the cluster a volume's data may be
+// written to.
+type capacityFilter struct {
+	dataCenter string
+	diskType   string
+	// copies is the number of replicas of every written volume
+	copies int
+}
+
+// replicaCopies returns how many copies of each volume a SeaweedFS
+// replication setting such as "001" keeps.
+func replicaCopies(replication string) (int, error) {
+	if replication == "" {
+		return 1, nil
+	}
+	if len(replication) != 3 {
+		return 0, fmt.Errorf("invalid replication %q", replication)
+	}
+	copies := 1
+	for _, c := range replication {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("invalid replication %q", replication)
+		}
+		copies += int(c - '0')
+	}
+	return copies, nil
+}
+
+// normalizeDiskType maps the names of the default disk type to the empty
+// string the master reports it as.
+func normalizeDiskType(diskType string) string {
+	if diskType == "hdd" {
+		return ""
+	}
+	return diskType
+}
+
+// availableCapacity estimates how many bytes a new volume can still store.
+// Every volume gets its own collection, so only volume slots not yet
+// allocated to any collection count: each can grow to the volume size limit
+// and holds one replica.
+func availableCapacity(topology *master_pb.TopologyInfo, volumeSizeLimitMb uint64, filter capacityFilter) int64 {
+	diskType := normalizeDiskType(filter.diskType)
+
+	var freeSlots int64
+	for _, dc := range topology.GetDataCenterInfos() {
+		if filter.dataCenter != "" && dc.Id != filter.dataCenter {
+			continue
+		}
+		for _, rack := range dc.RackInfos {
+			for _, node := range rack.DataNodeInfos {
+				for key, disk := range node.DiskInfos {
+					if normalizeDiskType(key) != diskType {
+						continue
+					}
+					if disk.FreeVolumeCount > 0 {
+						freeSlots += disk.FreeVolumeCount
+					}
+				}
+			}
+		}
+	}
+
+	copies := int64(filter.copies)
+	if copies < 1 {
+		copies = 1
+	}
+	return freeSlots / copies * int64(volumeSizeLimitMb) * 1024 * 1024
+}
+
+// clusterCapacity asks the masters how much space is available for a new
+// volume with the given StorageClass parameters.
+func clusterCapacity(ctx context.Context, d *SeaweedFsDriver, params map[string]string, dataCenter string) (int64, error) {
+	replication := params["replication"]
+	if replication == "" {
+		// fall back to the filer's default replication
+		err := d.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+			resp, err := client.GetFilerConfiguration(ctx, &filer_pb.GetFilerConfigurationRequest{})
+			if err != nil {
+				return err
+			}
+			replication = resp.Replication
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("get filer configuration: %v", err)
+		}
+	}
+	copies, err := replicaCopies(replication)
+	if err != nil {
+		return 0, err
+	}
+
+	if dataCenter == "" {
+		dataCenter = params["dataCenter"]
+	}
+	filter := capacityFilter{
+		dataCenter: dataCenter,
+		diskType:   params["diskType"],
+		copies:     copies,
+	}
+
+	var capacity int64
+	err = d.WithMasterClient(ctx, func(client master_pb.SeaweedClient) error {
+		resp, err := client.VolumeList(ctx, &master_pb.VolumeListRequest{})
+		if err != nil {
+			return err
+		}
+		capacity = availableCapacity(resp.TopologyInfo, resp.VolumeSizeLimitMb, filter)
+		return nil
+	})
+	return capacity, err
+}
@@ -0,0 +1,68 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+)
+
+func TestReplicaCopies(t *testing.T) {
+	tests := []struct {
+		replication string
+		want        int
+		wantErr     bool
+	}{
+		{"", 1, false},
+		{"000", 1, false},
+		{"001", 2, false},
+		{"110", 3, false},
+		{"01", 0, true},
+		{"00a", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := replicaCopies(tt.replication)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("replicaCopies(%q) = %d, %v; want %d, error %v", tt.replication, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAvailableCapacity(t *testing.T) {
+	node := func(id string, disks map[string]int64) *master_pb.DataNodeInfo {
+		info := &master_pb.DataNodeInfo{Id: id, DiskInfos: map[string]*master_pb.DiskInfo{}}
+		for diskType, free := range disks {
+			info.DiskInfos[diskType] = &master_pb.DiskInfo{Type: diskType, FreeVolumeCount: free}
+		}
+		return info
+	}
+	topology := &master_pb.TopologyInfo{
+		DataCenterInfos: []*master_pb.DataCenterInfo{
+			{Id: "dc1", RackInfos: []*master_pb.RackInfo{{Id: "r1", DataNodeInfos: []*master_pb.DataNodeInfo{
+				node("n1", map[string]int64{"": 4, "ssd": 2}),
+				node("n2", map[string]int64{"": 2}),
+			}}}},
+			{Id: "dc2", RackInfos: []*master_pb.RackInfo{{Id: "r1", DataNodeInfos: []*master_pb.DataNodeInfo{
+				node("n3", map[string]int64{"ssd": 8}),
+			}}}},
+		},
+	}
+	const mb = 1024 * 1024
+
+	tests := []struct {
+		name   string
+		filter capacityFilter
+		want   int64
+	}{
+		{"default disk type everywhere", capacityFilter{}, 6 * 100 * mb},
+		{"hdd is the default disk type", capacityFilter{diskType: "hdd"}, 6 * 100 * mb},
+		{"ssd everywhere", capacityFilter{diskType: "ssd"}, 10 * 100 * mb},
+		{"ssd in one data center", capacityFilter{diskType: "ssd", dataCenter: "dc1"}, 2 * 100 * mb},
+		{"replicated", capacityFilter{diskType: "ssd", copies: 2}, 5 * 100 * mb},
+		{"unknown data center", capacityFilter{dataCenter: "dc3"}, 0},
+	}
+	for _, tt := range tests {
+		if got := availableCapacity(topology, 100, tt.filter); got != tt.want {
+			t.Errorf("%s: availableCapacity = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
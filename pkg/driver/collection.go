@@ -0,0 +1,49 @@
+package driver
+
+import (
+	"context"
+	"path"
+	"strconv"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+)
+
+// deleteCollectionParam makes DeleteVolume drop the collection of a bucket
+// volume along with its directory, instead of leaving the collection's
+// volumes allocated on the volume servers.
+const deleteCollectionParam = "deleteCollection"
+
+// ownedCollection returns the collection that the volume volumeName, created
+// with params, holds on its own and that is to be dropped when the volume is
+// deleted, or "" if there is none. Only bucket volumes directly in
+// bucketsDir using the default collection own it: bucket names are unique,
+// while explicit collections and the collections of shared directories may
+// hold other volumes.
+func ownedCollection(params map[string]string, bucketsDir, volumeName string) string {
+	if drop, _ := strconv.ParseBool(params[deleteCollectionParam]); !drop {
+		return ""
+	}
+	if params["collection"] != "" || params[sharedDirParam] != "" {
+		return ""
+	}
+	parentDir := params["parentDir"]
+	if volumePath := params["path"]; volumePath != "" {
+		parentDir = path.Dir(path.Clean(volumePath))
+	} else if parentDir == "" {
+		parentDir = bucketsDir
+	}
+	if path.Clean(parentDir) != bucketsDir {
+		return ""
+	}
+	return volumeName
+}
+
+// dropCollection deletes collection and the volumes holding its data through
+// the master of the cluster client talks to. Dropping a collection that does
+// not exist succeeds.
+func dropCollection(ctx context.Context, client *SeaweedFsDriver, collection string) error {
+	return client.WithMasterClient(ctx, func(c master_pb.SeaweedClient) error {
+		_, err := c.CollectionDelete(ctx, &master_pb.CollectionDeleteRequest{Name: collection})
+		return err
+	})
+}
@@ -0,0 +1,32 @@
+package driver
+
+import "testing"
+
+func TestOwnedCollection(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{"default bucket", map[string]string{deleteCollectionParam: "true"}, "pvc-1234"},
+		{"explicit parentDir", map[string]string{deleteCollectionParam: "true", "parentDir": "/buckets/"}, "pvc-1234"},
+		{"explicit path", map[string]string{deleteCollectionParam: "true", "path": "/buckets/pvc-1234"}, "pvc-1234"},
+		{"not requested", map[string]string{}, ""},
+		{"disabled", map[string]string{deleteCollectionParam: "false"}, ""},
+		{"explicit collection", map[string]string{deleteCollectionParam: "true", "collection": "shared"}, ""},
+		{"shared dir", map[string]string{deleteCollectionParam: "true", sharedDirParam: "/buckets/shared"}, ""},
+		{"not a bucket", map[string]string{deleteCollectionParam: "true", "parentDir": "/data"}, ""},
+		{"nested path", map[string]string{deleteCollectionParam: "true", "path": "/buckets/team/pvc-1234"}, ""},
+	}
+	for _, tt := range tests {
+		if got := ownedCollection(tt.params, "/buckets", "pvc-1234"); got != tt.want {
+			t.Errorf("%s: ownedCollection() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := ownedCollection(map[string]string{deleteCollectionParam: "true"}, "/s3", "pvc-1234"); got != "pvc-1234" {
+		t.Errorf("ownedCollection() in a custom buckets directory = %q, want pvc-1234", got)
+	}
+	if got := ownedCollection(map[string]string{deleteCollectionParam: "true", "parentDir": "/buckets"}, "/s3", "pvc-1234"); got != "" {
+		t.Errorf("ownedCollection() outside the buckets directory = %q, want none", got)
+	}
+}
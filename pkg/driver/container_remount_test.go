@@ -263,7 +263,7 @@ func TestRecoverVolumeCallsContainerRemount(t *testing.T) {
 
 	volCtx := map[string]string{"collection": "c"}
 
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume: %v", err)
 	}
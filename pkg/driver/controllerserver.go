@@ -6,10 +6,14 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"maps"
 	"path"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/seaweedfs/seaweedfs/weed/glog"
@@ -25,6 +29,19 @@ type ControllerServer struct {
 	csi.UnimplementedControllerServer
 
 	Driver *SeaweedFsDriver
+
+	// volumeMutexes serializes metadata updates of a volume
+	volumeMutexes *KeyMutex
+	// snapshotMutexes serializes the requests for a snapshot name
+	snapshotMutexes *KeyMutex
+	// snapshotCopies are the snapshot copies running in the background
+	snapshotCopies backgroundCopies
+	// volumeCopies are the copies of content sources into new volumes
+	// running in the background
+	volumeCopies backgroundCopies
+
+	// stopCh stops the trash reaper
+	stopCh chan struct{}
 }
 
 var _ = csi.ControllerServer(&ControllerServer{})
@@ -32,7 +49,9 @@ var _ = csi.ControllerServer(&ControllerServer{})
 func (cs *ControllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (*csi.CreateVolumeResponse, error) {
 	glog.Infof("create volume req: %v", req.GetName())
 
-	params := req.GetParameters()
+	// params is extended with resolved values below, keep what was requested
+	requestParams := maps.Clone(req.GetParameters())
+	params := maps.Clone(requestParams)
 	if params == nil {
 		params = make(map[string]string)
 	}
@@ -44,20 +63,53 @@ func (cs *ControllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, status.Error(codes.InvalidArgument, "Name missing in request")
 	}
 
+	// Resolve the cluster the volume is provisioned in
+	cluster := clusterFromParams(params)
+	client, err := cs.Driver.forRequest(cluster, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Buckets are created where the filer's S3 gateway looks for them
+	bucketsDir, err := client.bucketsDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// Resolving path for volume
 	volumePath := params["path"]
 	var parentDir, volumeName string
-	if volumePath == "" {
+	if sharedDir := params[sharedDirParam]; sharedDir != "" {
+		// Subdirectory of an existing shared directory
+		if volumePath != "" || params["parentDir"] != "" {
+			return nil, status.Errorf(codes.InvalidArgument, "%s cannot be combined with path or parentDir", sharedDirParam)
+		}
+		if !path.IsAbs(sharedDir) {
+			return nil, status.Errorf(codes.InvalidArgument, "%s must be an absolute path", sharedDirParam)
+		}
+		subPath, err := expandPathPattern(params[pathPatternParam], requestedVolumeId, params)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		sharedDir = path.Clean(sharedDir)
+		if err := checkSharedDir(ctx, client, sharedDir); err != nil {
+			return nil, err
+		}
+		volumePath = path.Join(sharedDir, subPath)
+		parentDir = path.Dir(volumePath)
+		volumeName = path.Base(volumePath)
+		if params["collection"] == "" {
+			params["collection"] = sharedDirCollection(sharedDir, bucketsDir)
+		}
+	} else if volumePath == "" {
 		// If path is implicit, use provided parentDir, or default to creating buckets
-
-		// FIXME: need to use bucketDir in Filer config since it can be set to alternative paths
 		parentDir = params["parentDir"]
 		if parentDir == "" {
-			parentDir = "/buckets"
+			parentDir = bucketsDir
 		}
 
 		// Detect if this volume is a bucket by checking parentDir
-		if parentDir == "/buckets" {
+		if parentDir == bucketsDir {
 			volumeName = sanitizeVolumeIdS3(requestedVolumeId)
 		} else {
 			volumeName = requestedVolumeId
@@ -74,6 +126,14 @@ func (cs *ControllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 	params["parentDir"] = parentDir
 	params["volumeName"] = volumeName
 
+	// Pin writes to the data center the volume is requested in, unless the
+	// StorageClass names one explicitly
+	if params["dataCenter"] == "" {
+		if dataCenter := preferredDataCenter(req.GetAccessibilityRequirements()); dataCenter != "" {
+			params["dataCenter"] = dataCenter
+		}
+	}
+
 	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME); err != nil {
 		glog.V(3).Infof("invalid create volume req: %v", req)
 		return nil, err
@@ -83,28 +143,259 @@ func (cs *ControllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, status.Error(codes.InvalidArgument, "Volume Capabilities missing in request")
 	}
 
+	if err := validateVolumeParameters(params); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, capability := range req.GetVolumeCapabilities() {
+		if _, _, err := applyMountFlags(params, capability.GetMount().GetMountFlags()); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
 	capacity := req.GetCapacityRange().GetRequiredBytes()
 	if capacity > 0 {
 		params[volumeCapacityKey] = strconv.FormatInt(capacity, 10)
 	}
 
-	if err := filer_pb.Mkdir(ctx, cs.Driver, parentDir, volumeName, nil); err != nil {
-		return nil, fmt.Errorf("error creating volume: %v", err)
+	contentSource := req.GetVolumeContentSource()
+	contentPath, err := contentSourcePath(ctx, client, contentSource)
+	if err != nil {
+		return nil, err
+	}
+	meta := newVolumeMetadata(capacity, requestParams, contentPath)
+
+	// Serialize on the volume: the external-provisioner calls again until
+	// the volume is populated
+	volumeId := cluster.volumeId(volumePath)
+	volumeMutex := cs.volumeMutexes.GetMutex(volumeId)
+	volumeMutex.Lock()
+	defer volumeMutex.Unlock()
+
+	entry, err := lookupEntry(ctx, client, parentDir, volumeName)
+	if err != nil && err != errEntryNotFound {
+		return nil, status.Errorf(codes.Internal, "error looking up volume %s: %v", volumePath, err)
+	}
+
+	if err == errEntryNotFound {
+		var sourcePath string
+		if contentSource != nil {
+			if sourcePath, err = cs.resolveContentSource(ctx, cluster, client, contentSource, capacity); err != nil {
+				return nil, err
+			}
+			meta.ContentSource = sourcePath
+			meta.Populating = true
+		}
+
+		var storeErr error
+		if err := filer_pb.Mkdir(ctx, client, parentDir, volumeName, func(entry *filer_pb.Entry) {
+			storeErr = meta.store(entry)
+			if parentDir == bucketsDir {
+				// Same field as set by s3.bucket.quota, so that
+				// s3.bucket.quota.enforce also covers the volume
+				entry.Quota = capacity
+			}
+		}); err != nil {
+			return nil, fmt.Errorf("error creating volume: %v", err)
+		}
+		if storeErr != nil {
+			return nil, status.Errorf(codes.Internal, "error storing metadata of volume %s: %v", volumePath, storeErr)
+		}
+	} else {
+		if !entry.IsDirectory {
+			return nil, status.Errorf(codes.AlreadyExists, "%s already exists and is not a directory", volumePath)
+		}
+		existing, err := readVolumeMetadata(entry)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		switch {
+		case existing == nil:
+			// Directory created before metadata was recorded, or created
+			// by hand for static provisioning: adopt it as requested
+			if err := meta.store(entry); err != nil {
+				return nil, status.Errorf(codes.Internal, "error storing metadata of volume %s: %v", volumePath, err)
+			}
+			if err := updateEntry(ctx, client, parentDir, entry); err != nil {
+				return nil, status.Errorf(codes.Internal, "error storing metadata of volume %s: %v", volumePath, err)
+			}
+			glog.V(4).Infof("adopted existing directory %s as volume %s", volumePath, requestedVolumeId)
+		case !existing.matches(capacity, requestParams, meta.ContentSource):
+			return nil, status.Errorf(codes.AlreadyExists, "Volume %s already exists with different capacity, parameters or content source", requestedVolumeId)
+		default:
+			glog.V(4).Infof("volume %s already exists at %s", requestedVolumeId, volumePath)
+			meta = existing
+		}
+	}
+
+	if meta.Populating {
+		// The volume is returned once its content has been copied, until
+		// then the external-provisioner retries
+		if running := cs.volumeCopies.get(volumeId); running != nil {
+			if running.err == nil {
+				return nil, status.Errorf(codes.Aborted, "volume %s is still being populated from %s", volumePath, meta.ContentSource)
+			}
+			// report the failure once, the next call starts over
+			cs.volumeCopies.remove(volumeId)
+			return nil, status.Errorf(codes.Internal, "error populating volume %s from %s: %v", volumePath, meta.ContentSource, running.err)
+		}
+		// Also reached when a copy was interrupted by a restart of the
+		// controller: copying again overwrites whatever was already written
+		sourcePath, err := cs.resolveContentSource(ctx, cluster, client, contentSource, capacity)
+		if err != nil {
+			return nil, err
+		}
+		cs.populateVolume(volumeId, client, parentDir, volumeName, sourcePath, params)
+		return nil, status.Errorf(codes.Aborted, "volume %s is being populated from %s", volumePath, sourcePath)
 	}
 
 	glog.V(4).Infof("volume created %s at %s", requestedVolumeId, volumePath)
 
-	// Use full paths as VolumeID
-	// This keeps everything stateless
+	// Use full paths as VolumeID, prefixed with the cluster if it is not
+	// the driver's. This keeps everything stateless
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
-			VolumeId:      volumePath,
-			CapacityBytes: capacity,
+			VolumeId:      volumeId,
+			CapacityBytes: meta.CapacityBytes,
 			VolumeContext: params,
+			ContentSource: contentSource,
 		},
 	}, nil
 }
 
+// populateVolume copies sourcePath into the volume directory in the
+// background, then clears the volume's populating flag. Like copySnapshot,
+// the copy takes as long as the source's content does to copy, longer than
+// the external-provisioner waits for a CreateVolume call.
+func (cs *ControllerServer) populateVolume(volumeId string, client *SeaweedFsDriver, parentDir, volumeName, sourcePath string, params map[string]string) {
+	// Copy with the same storage options the mount will use for the volume
+	copyParams := map[string]string{
+		"collection":  params["collection"],
+		"replication": params["replication"],
+		"diskType":    params["diskType"],
+	}
+	if copyParams["collection"] == "" {
+		copyParams["collection"] = volumeName
+	}
+	volumePath := path.Join(parentDir, volumeName)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	running := &backgroundCopy{cancel: cancel, stopped: make(chan struct{})}
+	cs.volumeCopies.add(volumeId, running)
+
+	go func() {
+		defer cancel()
+
+		err := newTreeCopier(client, copyParams).copyDir(ctx, sourcePath, volumePath)
+		close(running.stopped)
+
+		volumeMutex := cs.volumeMutexes.GetMutex(volumeId)
+		volumeMutex.Lock()
+		defer volumeMutex.Unlock()
+		if cs.volumeCopies.get(volumeId) != running {
+			// the volume was deleted meanwhile
+			return
+		}
+		if err == nil {
+			err = updateVolumeMetadata(ctx, client, volumePath, func(meta *volumeMetadata) {
+				meta.Populating = false
+			})
+		}
+		if err != nil {
+			glog.Errorf("error populating volume %s from %s: %v", volumePath, sourcePath, err)
+			running.err = err
+			return
+		}
+		cs.volumeCopies.remove(volumeId)
+		glog.V(4).Infof("volume %s populated from %s", volumePath, sourcePath)
+	}()
+}
+
+// contentSourcePath returns the filer directory a content source refers to,
+// without checking that it exists. client talks to the cluster of the source.
+func contentSourcePath(ctx context.Context, client *SeaweedFsDriver, source *csi.VolumeContentSource) (string, error) {
+	if snapshotId := source.GetSnapshot().GetSnapshotId(); snapshotId != "" {
+		return snapshotId, nil
+	}
+	if volumeId := source.GetVolume().GetVolumeId(); volumeId != "" {
+		parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+		if err != nil {
+			return "", err
+		}
+		return path.Join(parentDir, volumeName), nil
+	}
+	return "", nil
+}
+
+// resolveContentSource checks that the snapshot or volume a new volume in
+// cluster is created from exists and returns the filer directory to copy
+// from. client talks to the filers of cluster.
+func (cs *ControllerServer) resolveContentSource(ctx context.Context, cluster filerCluster, client *SeaweedFsDriver, source *csi.VolumeContentSource, capacity int64) (string, error) {
+	if snapshotSource := source.GetSnapshot(); snapshotSource != nil {
+		if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT); err != nil {
+			return "", err
+		}
+		if !cluster.isDefault() {
+			return "", status.Error(codes.InvalidArgument, "Snapshots are only supported in the driver's filer cluster")
+		}
+		snapshotId := snapshotSource.GetSnapshotId()
+		snapshot, err := lookupSnapshot(ctx, client, snapshotId)
+		if err != nil {
+			return "", status.Errorf(codes.Internal, "error looking up snapshot %s: %v", snapshotId, err)
+		}
+		if snapshot == nil {
+			return "", status.Errorf(codes.NotFound, "Snapshot with id %s does not exist", snapshotId)
+		}
+		if !snapshot.readyToUse {
+			return "", status.Errorf(codes.Unavailable, "Snapshot %s is not ready to use", snapshotId)
+		}
+		if capacity > 0 && snapshot.sizeBytes > capacity {
+			return "", status.Errorf(codes.OutOfRange, "Snapshot %s size %d exceeds requested capacity %d", snapshotId, snapshot.sizeBytes, capacity)
+		}
+		return snapshot.snapshotId, nil
+	}
+
+	if volumeSource := source.GetVolume(); volumeSource != nil {
+		if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_CLONE_VOLUME); err != nil {
+			return "", err
+		}
+		sourceVolumeId := volumeSource.GetVolumeId()
+		if sourceCluster, _, err := parseVolumeId(sourceVolumeId); err != nil {
+			return "", status.Error(codes.NotFound, err.Error())
+		} else if sourceCluster != cluster {
+			return "", status.Errorf(codes.InvalidArgument, "Volume %s is in another filer cluster", sourceVolumeId)
+		}
+		sourceDir, sourceName, err := client.splitVolumeId(ctx, sourceVolumeId)
+		if err != nil {
+			return "", err
+		}
+		if _, err := lookupEntry(ctx, client, sourceDir, sourceName); err == errEntryNotFound {
+			return "", status.Errorf(codes.NotFound, "Volume with id %s does not exist", sourceVolumeId)
+		} else if err != nil {
+			return "", status.Errorf(codes.Internal, "error looking up volume %s: %v", sourceVolumeId, err)
+		}
+		return path.Join(sourceDir, sourceName), nil
+	}
+
+	return "", status.Error(codes.InvalidArgument, "Unsupported volume content source")
+}
+
+// checkSharedDir checks that the shared directory volumes are provisioned
+// in exists. It is not created on demand, since it is a volume of its own
+// whose collection the subdirectories share.
+func checkSharedDir(ctx context.Context, client filer_pb.FilerClient, sharedDir string) error {
+	entry, err := lookupEntry(ctx, client, path.Dir(sharedDir), path.Base(sharedDir))
+	if err == errEntryNotFound {
+		return status.Errorf(codes.FailedPrecondition, "shared directory %s does not exist", sharedDir)
+	}
+	if err != nil {
+		return status.Errorf(codes.Internal, "error looking up shared directory %s: %v", sharedDir, err)
+	}
+	if !entry.IsDirectory {
+		return status.Errorf(codes.FailedPrecondition, "shared directory %s is not a directory", sharedDir)
+	}
+	return nil
+}
+
 func (cs *ControllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVolumeRequest) (*csi.DeleteVolumeResponse, error) {
 	glog.Infof("delete volume req: %v", req.VolumeId)
 
@@ -121,17 +412,69 @@ func (cs *ControllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 	}
 	glog.V(4).Infof("deleting volume %s", volumeId)
 
-	var parentDir, volumeName string
-	if path.IsAbs(volumeId) {
-		parentDir = path.Dir(volumeId)
-		volumeName = path.Base(volumeId)
-	} else {
-		// Backward-compatibility with legacy volume ID
-		parentDir = "/buckets"
-		volumeName = volumeId
+	client, err := cs.Driver.forVolumeRequest(volumeId, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serialize with expansions and publications rewriting the metadata or
+	// quota of the volume; the lock is dropped along with the volume
+	defer cs.volumeMutexes.RemoveMutex(volumeId)
+	volumeMutex := cs.volumeMutexes.GetMutex(volumeId)
+	volumeMutex.Lock()
+	defer volumeMutex.Unlock()
+
+	// a copy still populating the volume would recreate what is removed
+	cs.volumeCopies.stop(volumeId)
+
+	meta, err := lookupVolumeMetadata(ctx, client, volumeId)
+	if err == errEntryNotFound {
+		glog.V(4).Infof("volume %s already deleted", volumeId)
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error reading volume %s: %v", volumeId, err)
+	}
+
+	if meta != nil && meta.Parameters[deletePolicyParam] == deletePolicyTrash {
+		trashDir := cs.Driver.trashDir()
+		trashPath, err := moveToTrash(ctx, client, trashDir, parentDir, volumeName, time.Now())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "error moving volume %s to trash: %v", volumeId, err)
+		}
+		glog.Infof("volume %s moved to trash as %s", volumeId, trashPath)
+		// the reaper only covers the default cluster
+		if cs.Driver.TrashRetention > 0 {
+			if err := purgeTrash(ctx, client, trashDir, cs.Driver.TrashRetention, time.Now()); err != nil {
+				glog.Warningf("purge trash of volume %s: %v", volumeId, err)
+			}
+		}
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+
+	// The collection goes first, so that a failure leaves the volume in place
+	// for the retried request to find its metadata
+	deleteData := true
+	if meta != nil {
+		bucketsDir, err := client.bucketsDir(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if collection := ownedCollection(meta.Parameters, bucketsDir, volumeName); collection != "" {
+			if err := dropCollection(ctx, client, collection); err != nil {
+				return nil, status.Errorf(codes.Internal, "error deleting collection %s of volume %s: %v", collection, volumeId, err)
+			}
+			glog.Infof("deleted collection %s of volume %s", collection, volumeId)
+			// the chunks went with the collection
+			deleteData = false
+		}
 	}
 
-	if err := filer_pb.Remove(ctx, cs.Driver, parentDir, volumeName, true, true, true, false, nil); err != nil {
+	if err := filer_pb.Remove(ctx, client, parentDir, volumeName, deleteData, true, true, false, nil); err != nil {
 		return nil, fmt.Errorf("error deleting volume %s: %v", volumeId, err)
 	}
 
@@ -154,6 +497,33 @@ func (cs *ControllerServer) ControllerPublishVolume(ctx context.Context, req *cs
 		return nil, status.Error(codes.InvalidArgument, "Node ID missing in request")
 	}
 
+	volumeMutex := cs.volumeMutexes.GetMutex(volumeId)
+	volumeMutex.Lock()
+	defer volumeMutex.Unlock()
+
+	client, err := cs.Driver.forVolumeRequest(volumeId, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the node for ListVolumes
+	err = updateVolumeMetadata(ctx, client, path.Join(parentDir, volumeName), func(meta *volumeMetadata) {
+		if !slices.Contains(meta.PublishedNodes, nodeId) {
+			meta.PublishedNodes = append(meta.PublishedNodes, nodeId)
+		}
+	})
+	if err == errEntryNotFound {
+		return nil, status.Errorf(codes.NotFound, "Volume with id %s does not exist", volumeId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error publishing volume %s to node %s: %v", volumeId, nodeId, err)
+	}
+
 	return &csi.ControllerPublishVolumeResponse{}, nil
 }
 
@@ -161,13 +531,39 @@ func (cs *ControllerServer) ControllerPublishVolume(ctx context.Context, req *cs
 func (cs *ControllerServer) ControllerUnpublishVolume(ctx context.Context, req *csi.ControllerUnpublishVolumeRequest) (*csi.ControllerUnpublishVolumeResponse, error) {
 	volumeId := req.VolumeId
 
-	glog.Infof("controller unpublish volume req: %s", req.VolumeId)
+	nodeId := req.NodeId
+
+	glog.Infof("controller unpublish volume req: %s, node: %s", req.VolumeId, nodeId)
 
 	// Check arguments
 	if len(volumeId) == 0 {
 		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
 	}
 
+	volumeMutex := cs.volumeMutexes.GetMutex(volumeId)
+	volumeMutex.Lock()
+	defer volumeMutex.Unlock()
+
+	client, err := cs.Driver.forVolumeRequest(volumeId, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty node ID unpublishes the volume from every node
+	err = updateVolumeMetadata(ctx, client, path.Join(parentDir, volumeName), func(meta *volumeMetadata) {
+		meta.PublishedNodes = slices.DeleteFunc(meta.PublishedNodes, func(node string) bool {
+			return nodeId == "" || node == nodeId
+		})
+	})
+	if err != nil && err != errEntryNotFound {
+		return nil, status.Errorf(codes.Internal, "error unpublishing volume %s from node %s: %v", volumeId, nodeId, err)
+	}
+
 	return &csi.ControllerUnpublishVolumeResponse{}, nil
 }
 
@@ -184,17 +580,16 @@ func (cs *ControllerServer) ValidateVolumeCapabilities(ctx context.Context, req
 		return nil, status.Error(codes.InvalidArgument, "Volume capabilities missing in request")
 	}
 
-	var parentDir, volumeName string
-	if path.IsAbs(volumeId) {
-		parentDir = path.Dir(volumeId)
-		volumeName = path.Base(volumeId)
-	} else {
-		// Backward-compatibility with legacy volume ID
-		parentDir = "/buckets"
-		volumeName = volumeId
+	client, err := cs.Driver.forVolumeRequest(volumeId, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return nil, err
 	}
 
-	exists, err := filer_pb.Exists(ctx, cs.Driver, parentDir, volumeName, true)
+	exists, err := filer_pb.Exists(ctx, client, parentDir, volumeName, true)
 	if err != nil {
 		return nil, fmt.Errorf("error checking bucket %s exists: %v", volumeId, err)
 	}
@@ -240,17 +635,456 @@ func (cs *ControllerServer) ControllerGetCapabilities(ctx context.Context, req *
 }
 
 func (cs *ControllerServer) ControllerExpandVolume(ctx context.Context, req *csi.ControllerExpandVolumeRequest) (*csi.ControllerExpandVolumeResponse, error) {
+	volumeId := req.GetVolumeId()
 	capacity := req.GetCapacityRange().GetRequiredBytes()
 
-	glog.Infof("expand volume req: %v, capacity: %v", req.GetVolumeId(), capacity)
+	glog.Infof("expand volume req: %v, capacity: %v", volumeId, capacity)
+
+	// Check arguments
+	if len(volumeId) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+	if req.GetCapacityRange() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Capacity range missing in request")
+	}
+	if limit := req.GetCapacityRange().GetLimitBytes(); limit > 0 && limit < capacity {
+		return nil, status.Errorf(codes.InvalidArgument, "Required bytes %d exceed limit bytes %d", capacity, limit)
+	}
+
+	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_EXPAND_VOLUME); err != nil {
+		glog.V(3).Infof("invalid expand volume req: %v", req)
+		return nil, err
+	}
+
+	// The metadata is read, modified and written back below
+	volumeMutex := cs.volumeMutexes.GetMutex(volumeId)
+	volumeMutex.Lock()
+	defer volumeMutex.Unlock()
+
+	client, err := cs.Driver.forVolumeRequest(volumeId, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := lookupEntry(ctx, client, parentDir, volumeName)
+	if err == errEntryNotFound {
+		return nil, status.Errorf(codes.NotFound, "Volume with id %s does not exist", volumeId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error looking up volume %s: %v", volumeId, err)
+	}
+	meta, err := readVolumeMetadata(entry)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if meta == nil {
+		meta = &volumeMetadata{CreationTime: time.Now().UTC()}
+	}
+
+	// Quotas only grow: a smaller request is already satisfied
+	if capacity < meta.CapacityBytes {
+		capacity = meta.CapacityBytes
+	}
+
+	// Record the new capacity centrally so it applies to volumes that are
+	// not staged anywhere; nodes read it back when staging
+	meta.CapacityBytes = capacity
+	if err := meta.store(entry); err != nil {
+		return nil, status.Errorf(codes.Internal, "error storing metadata of volume %s: %v", volumeId, err)
+	}
+	if bucketsDir, err := client.bucketsDir(ctx); err != nil {
+		return nil, err
+	} else if parentDir == bucketsDir {
+		entry.Quota = capacity
+	}
+	if err := updateEntry(ctx, client, parentDir, entry); err != nil {
+		return nil, status.Errorf(codes.Internal, "error updating volume %s: %v", volumeId, err)
+	}
 
-	// We need to propagate resize requests to node servers
+	// Mounts that are already staged only pick up the new quota through the
+	// node servers
 	return &csi.ControllerExpandVolumeResponse{
 		CapacityBytes:         capacity,
 		NodeExpansionRequired: true,
 	}, nil
 }
 
+func (cs *ControllerServer) ListVolumes(ctx context.Context, req *csi.ListVolumesRequest) (*csi.ListVolumesResponse, error) {
+	glog.V(4).Infof("list volumes req: %v", req)
+
+	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_LIST_VOLUMES); err != nil {
+		glog.V(3).Infof("invalid list volumes req: %v", req)
+		return nil, err
+	}
+
+	parentDirs, err := cs.Driver.volumeParentDirs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := listVolumes(ctx, cs.Driver, parentDirs)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error listing volumes: %v", err)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].volumeId < volumes[j].volumeId
+	})
+
+	start, end, nextToken, err := paginate(len(volumes), req.GetStartingToken(), req.GetMaxEntries())
+	if err != nil {
+		return nil, err
+	}
+
+	// Published nodes are only tracked when ControllerPublishVolume is called
+	reportNodes := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES) == nil
+
+	entries := make([]*csi.ListVolumesResponse_Entry, 0, end-start)
+	for _, volume := range volumes[start:end] {
+		entry := &csi.ListVolumesResponse_Entry{
+			Volume: &csi.Volume{
+				VolumeId:      volume.volumeId,
+				CapacityBytes: volume.meta.CapacityBytes,
+			},
+		}
+		if reportNodes {
+			entry.Status = &csi.ListVolumesResponse_VolumeStatus{
+				PublishedNodeIds: volume.meta.PublishedNodes,
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return &csi.ListVolumesResponse{
+		Entries:   entries,
+		NextToken: nextToken,
+	}, nil
+}
+
+func (cs *ControllerServer) ControllerGetVolume(ctx context.Context, req *csi.ControllerGetVolumeRequest) (*csi.ControllerGetVolumeResponse, error) {
+	volumeId := req.GetVolumeId()
+
+	glog.V(4).Infof("controller get volume req: %s", volumeId)
+
+	// Check arguments
+	if len(volumeId) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+
+	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_GET_VOLUME); err != nil {
+		glog.V(3).Infof("invalid controller get volume req: %v", req)
+		return nil, err
+	}
+
+	client, err := cs.Driver.forVolume(volumeId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := lookupEntry(ctx, client, parentDir, volumeName)
+	if err == errEntryNotFound {
+		return nil, status.Errorf(codes.NotFound, "Volume with id %s does not exist", volumeId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error looking up volume %s: %v", volumeId, err)
+	}
+	if !entry.IsDirectory {
+		return nil, status.Errorf(codes.NotFound, "Volume with id %s is not a directory", volumeId)
+	}
+	meta, err := readVolumeMetadata(entry)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if meta == nil {
+		meta = &volumeMetadata{}
+	}
+	bucketsDir, err := client.bucketsDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeStatus := &csi.ControllerGetVolumeResponse_VolumeStatus{
+		VolumeCondition: volumeCondition(ctx, client, bucketsDir, parentDir, volumeName, meta.Parameters),
+	}
+	if cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES) == nil {
+		volumeStatus.PublishedNodeIds = meta.PublishedNodes
+	}
+
+	return &csi.ControllerGetVolumeResponse{
+		Volume: &csi.Volume{
+			VolumeId:      volumeId,
+			CapacityBytes: meta.CapacityBytes,
+		},
+		Status: volumeStatus,
+	}, nil
+}
+
+func (cs *ControllerServer) GetCapacity(ctx context.Context, req *csi.GetCapacityRequest) (*csi.GetCapacityResponse, error) {
+	glog.V(4).Infof("get capacity req: %v", req)
+
+	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_GET_CAPACITY); err != nil {
+		glog.V(3).Infof("invalid get capacity req: %v", req)
+		return nil, err
+	}
+
+	params := req.GetParameters()
+	if _, err := replicaCopies(params["replication"]); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	dataCenter := topologyDataCenter(req.GetAccessibleTopology())
+	if paramDataCenter := params["dataCenter"]; paramDataCenter != "" && dataCenter != "" && paramDataCenter != dataCenter {
+		// Volumes of this class are never written to the requested segment
+		return &csi.GetCapacityResponse{}, nil
+	}
+
+	client, err := cs.Driver.forCluster(clusterFromParams(params))
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	capacity, err := clusterCapacity(ctx, client, params, dataCenter)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "error reading cluster capacity: %v", err)
+	}
+
+	return &csi.GetCapacityResponse{
+		AvailableCapacity: capacity,
+	}, nil
+}
+
+func (cs *ControllerServer) CreateSnapshot(ctx context.Context, req *csi.CreateSnapshotRequest) (*csi.CreateSnapshotResponse, error) {
+	glog.Infof("create snapshot req: %v, source volume: %v", req.GetName(), req.GetSourceVolumeId())
+
+	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT); err != nil {
+		glog.V(3).Infof("invalid create snapshot req: %v", req)
+		return nil, err
+	}
+
+	// Check arguments
+	name := req.GetName()
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "Name missing in request")
+	}
+	if _, base, ok := parseSnapshotId(path.Join(snapshotsDir, name)); !ok || base != name {
+		return nil, status.Errorf(codes.InvalidArgument, "Snapshot name %s is not a valid file name", name)
+	}
+	sourceVolumeId := req.GetSourceVolumeId()
+	if sourceVolumeId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Source volume ID missing in request")
+	}
+
+	if cluster, _, err := parseVolumeId(sourceVolumeId); err != nil || !cluster.isDefault() {
+		return nil, status.Errorf(codes.InvalidArgument, "Volume %s is not in the driver's filer cluster, snapshots are not supported", sourceVolumeId)
+	}
+	client, err := cs.Driver.forRequest(filerCluster{}, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sourceDir, sourceName, err := client.splitVolumeId(ctx, sourceVolumeId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := lookupEntry(ctx, client, sourceDir, sourceName); err == errEntryNotFound {
+		return nil, status.Errorf(codes.NotFound, "Volume with id %s does not exist", sourceVolumeId)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "error looking up volume %s: %v", sourceVolumeId, err)
+	}
+
+	// Serialize on the name: the external-snapshotter calls again until
+	// the snapshot is ready
+	snapshotMutex := cs.snapshotMutexes.GetMutex(name)
+	snapshotMutex.Lock()
+	defer snapshotMutex.Unlock()
+
+	snapshotId := path.Join(snapshotsDir, name)
+	existing, err := lookupSnapshot(ctx, client, snapshotId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error looking up snapshot %s: %v", name, err)
+	}
+	if existing != nil {
+		if existing.sourceVolumeId != sourceVolumeId {
+			return nil, status.Errorf(codes.AlreadyExists, "Snapshot %s already exists for volume %s", name, existing.sourceVolumeId)
+		}
+		if existing.readyToUse {
+			return &csi.CreateSnapshotResponse{Snapshot: existing.toCSI()}, nil
+		}
+		if running := cs.snapshotCopies.get(snapshotId); running != nil {
+			if running.err == nil {
+				return &csi.CreateSnapshotResponse{Snapshot: existing.toCSI()}, nil
+			}
+			// report the failure once, the next call starts over
+			cs.snapshotCopies.remove(snapshotId)
+			return nil, status.Errorf(codes.Internal, "error copying volume %s to snapshot %s: %v", sourceVolumeId, snapshotId, running.err)
+		}
+		// left behind by a copy that was interrupted by a restart of the
+		// controller, start over
+		glog.Infof("removing incomplete snapshot %s", snapshotId)
+		if err := filer_pb.Remove(ctx, client, snapshotsDir, name, true, true, true, false, nil); err != nil {
+			return nil, status.Errorf(codes.Internal, "error removing incomplete snapshot %s: %v", snapshotId, err)
+		}
+	}
+
+	snapshot := &snapshotInfo{
+		snapshotId:     snapshotId,
+		sourceVolumeId: sourceVolumeId,
+		creationTime:   time.Now(),
+	}
+
+	// Record the source before copying so an interrupted copy is found and
+	// cleaned up when the request is retried.
+	if err := filer_pb.Mkdir(ctx, client, snapshotsDir, name, func(entry *filer_pb.Entry) {
+		entry.Extended = map[string][]byte{
+			snapshotSourceVolumeKey: []byte(sourceVolumeId),
+			snapshotCreationTimeKey: []byte(snapshot.creationTime.Format(time.RFC3339Nano)),
+		}
+	}); err != nil {
+		return nil, status.Errorf(codes.Internal, "error creating snapshot %s: %v", snapshotId, err)
+	}
+
+	cs.copySnapshot(client, snapshot, path.Join(sourceDir, sourceName), req.GetParameters())
+
+	return &csi.CreateSnapshotResponse{Snapshot: snapshot.toCSI()}, nil
+}
+
+// copySnapshot copies sourcePath into snapshot in the background and marks
+// the snapshot ready once done. The copy takes as long as the volume's
+// content does to copy, so CreateSnapshot reports the snapshot as not ready
+// until then rather than blocking.
+func (cs *ControllerServer) copySnapshot(client *SeaweedFsDriver, snapshot *snapshotInfo, sourcePath string, params map[string]string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	running := &backgroundCopy{cancel: cancel, stopped: make(chan struct{})}
+	cs.snapshotCopies.add(snapshot.snapshotId, running)
+
+	go func() {
+		defer cancel()
+
+		copier := newTreeCopier(client, params)
+		err := copier.copyDir(ctx, sourcePath, snapshot.snapshotId)
+		close(running.stopped)
+
+		_, name, _ := parseSnapshotId(snapshot.snapshotId)
+		snapshotMutex := cs.snapshotMutexes.GetMutex(name)
+		snapshotMutex.Lock()
+		defer snapshotMutex.Unlock()
+		if cs.snapshotCopies.get(snapshot.snapshotId) != running {
+			// the snapshot was deleted meanwhile
+			return
+		}
+		if err == nil {
+			err = completeSnapshot(ctx, client, snapshot.snapshotId, copier.copiedBytes)
+		}
+		if err != nil {
+			glog.Errorf("error copying volume %s to snapshot %s: %v", snapshot.sourceVolumeId, snapshot.snapshotId, err)
+			running.err = err
+			return
+		}
+		cs.snapshotCopies.remove(snapshot.snapshotId)
+		glog.V(4).Infof("snapshot %s of volume %s created, %d bytes", snapshot.snapshotId, snapshot.sourceVolumeId, copier.copiedBytes)
+	}()
+}
+
+func (cs *ControllerServer) DeleteSnapshot(ctx context.Context, req *csi.DeleteSnapshotRequest) (*csi.DeleteSnapshotResponse, error) {
+	snapshotId := req.GetSnapshotId()
+
+	glog.Infof("delete snapshot req: %v", snapshotId)
+
+	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT); err != nil {
+		glog.V(3).Infof("invalid delete snapshot req: %v", req)
+		return nil, err
+	}
+
+	// Check arguments
+	if snapshotId == "" {
+		return nil, status.Error(codes.InvalidArgument, "Snapshot ID missing in request")
+	}
+
+	client, err := cs.Driver.forRequest(filerCluster{}, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	dir, name, ok := parseSnapshotId(snapshotId)
+	if !ok {
+		glog.V(4).Infof("snapshot %s does not exist", snapshotId)
+		return &csi.DeleteSnapshotResponse{}, nil
+	}
+	snapshotMutex := cs.snapshotMutexes.GetMutex(name)
+	snapshotMutex.Lock()
+	defer snapshotMutex.Unlock()
+
+	// a copy still running would recreate what is removed
+	cs.snapshotCopies.stop(snapshotId)
+
+	snapshot, err := lookupSnapshot(ctx, client, snapshotId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "error looking up snapshot %s: %v", snapshotId, err)
+	}
+	if snapshot == nil {
+		glog.V(4).Infof("snapshot %s does not exist", snapshotId)
+		return &csi.DeleteSnapshotResponse{}, nil
+	}
+
+	if err := filer_pb.Remove(ctx, client, dir, name, true, true, true, false, nil); err != nil {
+		return nil, status.Errorf(codes.Internal, "error deleting snapshot %s: %v", snapshotId, err)
+	}
+
+	return &csi.DeleteSnapshotResponse{}, nil
+}
+
+func (cs *ControllerServer) ListSnapshots(ctx context.Context, req *csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
+	glog.V(3).Infof("list snapshots req: snapshot %q, source volume %q", req.GetSnapshotId(), req.GetSourceVolumeId())
+
+	if err := cs.Driver.ValidateControllerServiceRequest(csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS); err != nil {
+		glog.V(3).Infof("invalid list snapshots req: %v", req)
+		return nil, err
+	}
+
+	client, err := cs.Driver.forRequest(filerCluster{}, req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	var snapshots []*snapshotInfo
+	if snapshotId := req.GetSnapshotId(); snapshotId != "" {
+		snapshot, err := lookupSnapshot(ctx, client, snapshotId)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "error looking up snapshot %s: %v", snapshotId, err)
+		}
+		if snapshot != nil && (req.GetSourceVolumeId() == "" || snapshot.sourceVolumeId == req.GetSourceVolumeId()) {
+			snapshots = append(snapshots, snapshot)
+		}
+	} else {
+		snapshots, err = listSnapshots(ctx, client, req.GetSourceVolumeId())
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "error listing snapshots: %v", err)
+		}
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].snapshotId < snapshots[j].snapshotId
+	})
+
+	start, end, nextToken, err := paginate(len(snapshots), req.GetStartingToken(), req.GetMaxEntries())
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*csi.ListSnapshotsResponse_Entry, 0, end-start)
+	for _, snapshot := range snapshots[start:end] {
+		entries = append(entries, &csi.ListSnapshotsResponse_Entry{Snapshot: snapshot.toCSI()})
+	}
+
+	return &csi.ListSnapshotsResponse{
+		Entries:   entries,
+		NextToken: nextToken,
+	}, nil
+}
+
 func sanitizeVolumeIdS3(volumeId string) string {
 	volumeId = strings.ToLower(volumeId)
 	// NOTE: leave original length-only logic to ensure backward compatibility with volumes
@@ -283,6 +1117,11 @@ func sanitizeVolumeIdS3(volumeId string) string {
 	return volumeId
 }
 
+// ControllerCleanup stops the trash reaper.
+func (cs *ControllerServer) ControllerCleanup() {
+	close(cs.stopCh)
+}
+
 func isValidVolumeCapabilities(driverVolumeCaps []*csi.VolumeCapability_AccessMode, volCaps []*csi.VolumeCapability) bool {
 	hasSupport := func(cap *csi.VolumeCapability) bool {
 		for _, c := range driverVolumeCaps {
@@ -0,0 +1,52 @@
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestResolveContentSourceRequiresCloneCapability(t *testing.T) {
+	cs := &ControllerServer{Driver: &SeaweedFsDriver{}}
+	cs.Driver.AddControllerServiceCapabilities([]csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
+	})
+
+	source := &csi.VolumeContentSource{
+		Type: &csi.VolumeContentSource_Volume{
+			Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: "/buckets/golden"},
+		},
+	}
+	if _, err := cs.resolveContentSource(context.Background(), filerCluster{}, cs.Driver, source, 0); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without CLONE_VOLUME capability, got %v", err)
+	}
+}
+
+func TestResolveContentSourceRejectsEmptySource(t *testing.T) {
+	cs := &ControllerServer{Driver: &SeaweedFsDriver{}}
+
+	if _, err := cs.resolveContentSource(context.Background(), filerCluster{}, cs.Driver, &csi.VolumeContentSource{}, 0); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an empty content source, got %v", err)
+	}
+}
+
+func TestControllerExpandVolumeValidatesArguments(t *testing.T) {
+	cs := &ControllerServer{Driver: &SeaweedFsDriver{}}
+	cs.Driver.AddControllerServiceCapabilities([]csi.ControllerServiceCapability_RPC_Type{
+		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+	})
+
+	tests := []*csi.ControllerExpandVolumeRequest{
+		{CapacityRange: &csi.CapacityRange{RequiredBytes: 1}},
+		{VolumeId: "/buckets/vol"},
+		{VolumeId: "/buckets/vol", CapacityRange: &csi.CapacityRange{RequiredBytes: 2, LimitBytes: 1}},
+	}
+	for _, req := range tests {
+		if _, err := cs.ControllerExpandVolume(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for %v, got %v", req, err)
+		}
+	}
+}
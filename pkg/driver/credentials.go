@@ -0,0 +1,108 @@
+package driver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Keys of the provisioner, controller and node-stage secrets holding filer
+// credentials.
+const (
+	secretTLSCert             = "tls.crt"
+	secretTLSKey              = "tls.key"
+	secretCACert              = "ca.crt"
+	secretFilerSigningKey     = "jwt.filer_signing.key"
+	secretFilerReadSigningKey = "jwt.filer_signing.read.key"
+)
+
+// credentialsFromSecrets returns the filer credentials in the secrets of a
+// request, or nil if there are none and security.toml applies.
+func credentialsFromSecrets(secrets map[string]string) (*mountmanager.MountCredentials, error) {
+	creds := &mountmanager.MountCredentials{
+		TLSCert:             secrets[secretTLSCert],
+		TLSKey:              secrets[secretTLSKey],
+		CACert:              secrets[secretCACert],
+		FilerSigningKey:     secrets[secretFilerSigningKey],
+		FilerReadSigningKey: secrets[secretFilerReadSigningKey],
+	}
+	if *creds == (mountmanager.MountCredentials{}) {
+		return nil, nil
+	}
+	if (creds.TLSCert == "") != (creds.TLSKey == "") {
+		return nil, fmt.Errorf("secrets must contain both %s and %s", secretTLSCert, secretTLSKey)
+	}
+	if creds.CACert != "" && creds.TLSCert == "" {
+		return nil, fmt.Errorf("secret %s needs a client certificate in %s", secretCACert, secretTLSCert)
+	}
+	return creds, nil
+}
+
+// clientTLSConfig returns the TLS configuration using the client certificate
+// of creds.
+func clientTLSConfig(creds *mountmanager.MountCredentials) (*tls.Config, error) {
+	cert, err := tls.X509KeyPair([]byte(creds.TLSCert), []byte(creds.TLSKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %v", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if creds.CACert != "" {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM([]byte(creds.CACert)) {
+			return nil, fmt.Errorf("invalid %s", secretCACert)
+		}
+	}
+	return config, nil
+}
+
+// forRequest returns a driver talking to the filers of cluster with the
+// credentials in the secrets of a request. Drivers with credentials are built
+// for the request and dropped with it rather than shared, so that secrets do
+// not outlive the requests carrying them; for the same reason their gRPC
+// connections are dialed for each call and closed after it.
+func (d *SeaweedFsDriver) forRequest(cluster filerCluster, secrets map[string]string) (*SeaweedFsDriver, error) {
+	creds, err := credentialsFromSecrets(secrets)
+	if err != nil {
+		return nil, err
+	}
+	return d.forCredentials(cluster, creds)
+}
+
+// forCredentials returns a driver talking to the filers of cluster with
+// creds, or with those of security.toml if creds is nil.
+func (d *SeaweedFsDriver) forCredentials(cluster filerCluster, creds *mountmanager.MountCredentials) (*SeaweedFsDriver, error) {
+	clusterDriver, err := d.forCluster(cluster)
+	if err != nil || creds == nil {
+		return clusterDriver, err
+	}
+
+	credentialedDriver := clusterDriver.withFilers(clusterDriver.filers, clusterDriver.grpcDialOption)
+	credentialedDriver.grpcConnections = clusterDriver.grpcConnections
+	if creds.TLSCert != "" {
+		tlsConfig, err := clientTLSConfig(creds)
+		if err != nil {
+			return nil, err
+		}
+		grpcDialOption := grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+		credentialedDriver.grpcDialOption = grpcDialOption
+		credentialedDriver.grpcConnections = newGrpcConnections(grpcDialOption, false)
+		credentialedDriver.filerHttp = clusterDriver.filerHttp.withClientTLS(tlsConfig)
+	}
+	credentialedDriver.filerSigningKey = creds.FilerSigningKey
+	credentialedDriver.filerReadSigningKey = creds.FilerReadSigningKey
+	return credentialedDriver, nil
+}
+
+// forVolumeRequest returns a driver for the volume volumeId using the
+// credentials in the secrets of a request.
+func (d *SeaweedFsDriver) forVolumeRequest(volumeId string, secrets map[string]string) (*SeaweedFsDriver, error) {
+	cluster, _, err := parseVolumeId(volumeId)
+	if err != nil {
+		return nil, err
+	}
+	return d.forRequest(cluster, secrets)
+}
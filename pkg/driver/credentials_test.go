@@ -0,0 +1,169 @@
+package driver
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+)
+
+// testKeyPair returns a self-signed PEM certificate and key for commonName,
+// valid for 127.0.0.1.
+func testKeyPair(t *testing.T, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestCredentialsFromSecrets(t *testing.T) {
+	if creds, err := credentialsFromSecrets(map[string]string{"unrelated": "x"}); creds != nil || err != nil {
+		t.Fatalf("credentialsFromSecrets without credentials = %v, %v", creds, err)
+	}
+
+	creds, err := credentialsFromSecrets(map[string]string{secretFilerSigningKey: "k"})
+	if err != nil || creds == nil || creds.FilerSigningKey != "k" {
+		t.Fatalf("credentialsFromSecrets = %+v, %v", creds, err)
+	}
+
+	for _, secrets := range []map[string]string{
+		{secretTLSCert: "cert"},
+		{secretTLSKey: "key"},
+		{secretCACert: "ca"},
+	} {
+		if _, err := credentialsFromSecrets(secrets); err == nil {
+			t.Fatalf("credentialsFromSecrets(%v) succeeded, want error", secrets)
+		}
+	}
+}
+
+func TestForRequestUsesSecrets(t *testing.T) {
+	d := &SeaweedFsDriver{name: "test", filers: []pb.ServerAddress{"filer:8888"}, filerHttp: newFilerHttpClient(nil)}
+
+	if got, _ := d.forRequest(filerCluster{}, nil); got != d {
+		t.Fatalf("forRequest without secrets returned another driver")
+	}
+
+	cert, key := testKeyPair(t, "tenant-a")
+	secrets := map[string]string{secretTLSCert: cert, secretTLSKey: key, secretCACert: cert, secretFilerSigningKey: "write"}
+	first, err := d.forRequest(filerCluster{}, secrets)
+	if err != nil {
+		t.Fatalf("forRequest: %v", err)
+	}
+	if first == d || first.grpcDialOption == nil || first.filerSigningKey != "write" || len(first.filers) != 1 {
+		t.Fatalf("unexpected driver for secrets: %+v", first)
+	}
+	if second, _ := d.forRequest(filerCluster{}, secrets); second == first {
+		t.Fatalf("forRequest shared the driver of a previous request")
+	}
+	d.clusters.Range(func(key, _ any) bool {
+		t.Fatalf("forRequest kept a driver for %v", key)
+		return false
+	})
+	if other, _ := d.forRequest(filerCluster{}, map[string]string{secretFilerSigningKey: "other"}); other == first {
+		t.Fatalf("forRequest reused the driver of other secrets")
+	}
+
+	secrets[secretTLSKey] = "not a key"
+	if _, err := d.forRequest(filerCluster{}, secrets); err == nil {
+		t.Fatalf("forRequest accepted an invalid key pair")
+	}
+}
+
+// startTLSServer starts a gRPC server requiring client certificates and
+// returns its address, its PEM certificate and the common names of the
+// clients of its calls.
+func startTLSServer(t *testing.T) (string, string, func() []string) {
+	certPEM, keyPEM := testKeyPair(t, "server")
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var clients []string
+	recordClient := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if p, ok := peer.FromContext(ctx); ok {
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+				mu.Lock()
+				clients = append(clients, tlsInfo.State.PeerCertificates[0].Subject.CommonName)
+				mu.Unlock()
+			}
+		}
+		return handler(ctx, req)
+	}
+	server := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{cert}, ClientAuth: tls.RequireAnyClientCert})),
+		grpc.UnaryInterceptor(recordClient),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String(), certPEM, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(clients)
+	}
+}
+
+func TestRequestDriversDialWithTheirOwnCredentials(t *testing.T) {
+	address, serverCert, clients := startTLSServer(t)
+	d := &SeaweedFsDriver{name: "test", filers: []pb.ServerAddress{"filer:8888"}, filerHttp: newFilerHttpClient(nil)}
+
+	tenants := []string{"tenant-a", "tenant-b", "tenant-a"}
+	for _, tenant := range tenants {
+		cert, key := testKeyPair(t, tenant)
+		requestDriver, err := d.forRequest(filerCluster{}, map[string]string{secretTLSCert: cert, secretTLSKey: key, secretCACert: serverCert})
+		if err != nil {
+			t.Fatalf("forRequest: %v", err)
+		}
+		err = requestDriver.withGrpcClient(context.Background(), false, address, func(conn *grpc.ClientConn) error {
+			_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("call as %s: %v", tenant, err)
+		}
+	}
+	if got := clients(); !slices.Equal(got, tenants) {
+		t.Fatalf("calls were made as %v, want %v", got, tenants)
+	}
+}
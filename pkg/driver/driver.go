@@ -2,11 +2,14 @@ package driver
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/datalocality"
@@ -14,6 +17,7 @@ import (
 	"github.com/seaweedfs/seaweedfs/weed/glog"
 	"github.com/seaweedfs/seaweedfs/weed/pb"
 	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
 	"github.com/seaweedfs/seaweedfs/weed/security"
 	"github.com/seaweedfs/seaweedfs/weed/util"
 	"google.golang.org/grpc"
@@ -37,19 +41,56 @@ type SeaweedFsDriver struct {
 	vcap  []*csi.VolumeCapability_AccessMode
 	cscap []*csi.ControllerServiceCapability
 
-	filers            []pb.ServerAddress
-	filerIndex        int
-	grpcDialOption    grpc.DialOption
+	filers         []pb.ServerAddress
+	filerIndex     int
+	grpcDialOption grpc.DialOption
+	// grpcConnections replaces the connection cache of pb.WithGrpcClient for
+	// drivers dialing with other credentials than the node's
+	grpcConnections   *grpcConnections
 	ConcurrentWriters int
 	ConcurrentReaders int
 	CacheCapacityMB   int
 	CacheMetaTtlSec   int
 	CacheDir          string
-	UidMap            string
-	GidMap            string
-	signature         int32
-	DataCenter        string
-	DataLocality      datalocality.DataLocality
+	// CacheRoots are alternate directories volumes may place their cache on
+	CacheRoots []string
+	// MaxCacheCapacityMB, MaxConcurrentReaders and MaxConcurrentWriters cap
+	// the per-volume values, 0 means no maximum
+	MaxCacheCapacityMB   int
+	MaxConcurrentReaders int
+	MaxConcurrentWriters int
+	UidMap               string
+	GidMap               string
+	signature            int32
+	DataCenter           string
+	Rack                 string
+	DataLocality         datalocality.DataLocality
+	// VolumeParentDirs are the directories ListVolumes looks for volumes in
+	VolumeParentDirs []string
+	// TrashDir is where volumes with the trash delete policy are moved to,
+	// and TrashRetention how long they are kept there, 0 keeps them forever
+	TrashDir       string
+	TrashRetention time.Duration
+
+	// filer JWT signing keys of credentials given by request secrets, the
+	// security.toml keys apply when empty
+	filerSigningKey     string
+	filerReadSigningKey string
+	// filerHttp copies file content through the filer HTTP API
+	filerHttp *filerHttpClient
+
+	// dirBuckets caches the buckets directory reported by the filers
+	bucketsDirMutex sync.Mutex
+	dirBuckets      string
+
+	// clusters holds the drivers of the filer clusters selected by volumes
+	// and requests, keyed by filerCluster
+	clusters sync.Map
+
+	// mountClient is the client of the mount service shared by the mounts,
+	// so that its API is negotiated and connected to once
+	mountClientMutex sync.Mutex
+	mountClient      *mountmanager.Client
 
 	RunNode       bool
 	RunController bool
@@ -84,6 +125,13 @@ func NewSeaweedFsDriver(name, filer, nodeID, endpoint, mountEndpoint string, ena
 		signature:       util.RandomInt32(),
 	}
 
+	filerHttp, err := loadFilerHttpClient("https.client")
+	if err != nil {
+		glog.Errorf("https.client in security.toml: %v", err)
+		filerHttp = newFilerHttpClient(&tls.Config{})
+	}
+	n.filerHttp = filerHttp
+
 	n.AddVolumeCapabilityAccessModes([]csi.VolumeCapability_AccessMode_Mode{
 		csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER,
 		csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
@@ -94,12 +142,20 @@ func NewSeaweedFsDriver(name, filer, nodeID, endpoint, mountEndpoint string, ena
 		csi.ControllerServiceCapability_RPC_CREATE_DELETE_VOLUME,
 		csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
 		csi.ControllerServiceCapability_RPC_EXPAND_VOLUME,
+		csi.ControllerServiceCapability_RPC_CREATE_DELETE_SNAPSHOT,
+		csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
+		csi.ControllerServiceCapability_RPC_CLONE_VOLUME,
+		csi.ControllerServiceCapability_RPC_GET_CAPACITY,
+		csi.ControllerServiceCapability_RPC_LIST_VOLUMES,
+		csi.ControllerServiceCapability_RPC_GET_VOLUME,
+		csi.ControllerServiceCapability_RPC_VOLUME_CONDITION,
 	})
 
 	// we need this just only for csi-attach, but we do nothing for attach/detach
 	if enableAttacher {
 		n.AddControllerServiceCapabilities([]csi.ControllerServiceCapability_RPC_Type{
 			csi.ControllerServiceCapability_RPC_PUBLISH_UNPUBLISH_VOLUME,
+			csi.ControllerServiceCapability_RPC_LIST_VOLUMES_PUBLISHED_NODES,
 		})
 	}
 
@@ -112,6 +168,9 @@ func (n *SeaweedFsDriver) Run() {
 	var controller *ControllerServer
 	if n.RunController {
 		controller = NewControllerServer(n)
+		if n.TrashRetention > 0 {
+			controller.startTrashReaper(trashReapInterval)
+		}
 	}
 
 	var node *NodeServer
@@ -134,6 +193,10 @@ func (n *SeaweedFsDriver) Run() {
 	s.Stop()
 	s.Wait()
 
+	if controller != nil {
+		controller.ControllerCleanup()
+	}
+
 	if node != nil {
 		glog.Infof("node cleanup")
 		node.NodeCleanup()
@@ -180,10 +243,10 @@ func (d *SeaweedFsDriver) WithFilerClient(streamingMode bool, fn func(filer_pb.S
 		var err error
 		for x := 0; x < n; x++ {
 
-			err = pb.WithGrpcClient(context.Background(), streamingMode, d.signature, func(grpcConnection *grpc.ClientConn) error {
+			err = d.withGrpcClient(context.Background(), streamingMode, d.filers[i].ToGrpcAddress(), func(grpcConnection *grpc.ClientConn) error {
 				client := filer_pb.NewSeaweedFilerClient(grpcConnection)
 				return fn(client)
-			}, d.filers[i].ToGrpcAddress(), false, d.grpcDialOption)
+			})
 
 			if err != nil {
 				glog.V(0).Infof("WithFilerClient %d %v: %v", x, d.filers[i], err)
@@ -202,6 +265,73 @@ func (d *SeaweedFsDriver) WithFilerClient(streamingMode bool, fn func(filer_pb.S
 	})
 
 }
+
+// WithMasterClient runs fn against the first reachable master of the
+// cluster, as reported by the filer.
+func (d *SeaweedFsDriver) WithMasterClient(ctx context.Context, fn func(master_pb.SeaweedClient) error) error {
+	var masters []string
+	err := d.WithFilerClient(false, func(client filer_pb.SeaweedFilerClient) error {
+		resp, err := client.GetFilerConfiguration(ctx, &filer_pb.GetFilerConfigurationRequest{})
+		if err != nil {
+			return err
+		}
+		masters = resp.Masters
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("get filer configuration: %v", err)
+	}
+	if len(masters) == 0 {
+		return fmt.Errorf("filer reported no masters")
+	}
+
+	for _, master := range masters {
+		err = d.withGrpcClient(ctx, false, pb.ServerAddress(master).ToGrpcAddress(), func(grpcConnection *grpc.ClientConn) error {
+			return fn(master_pb.NewSeaweedClient(grpcConnection))
+		})
+		if err == nil {
+			return nil
+		}
+		glog.V(0).Infof("WithMasterClient %v: %v", master, err)
+	}
+	return err
+}
+
+// withGrpcClient runs fn with a connection to address dialed with the
+// credentials of the driver.
+func (d *SeaweedFsDriver) withGrpcClient(ctx context.Context, streamingMode bool, address string, fn func(*grpc.ClientConn) error) error {
+	if d.grpcConnections == nil {
+		return pb.WithGrpcClient(ctx, streamingMode, d.signature, fn, address, false, d.grpcDialOption)
+	}
+	return d.grpcConnections.with(address, fn)
+}
+
+func (d *SeaweedFsDriver) volumeParentDirs(ctx context.Context) ([]string, error) {
+	if len(d.VolumeParentDirs) == 0 {
+		bucketsDir, err := d.bucketsDir(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []string{bucketsDir}, nil
+	}
+	return d.VolumeParentDirs, nil
+}
+
+// mountServiceClient returns the client of the mount service, created on
+// first use and kept for the lifetime of the driver.
+func (d *SeaweedFsDriver) mountServiceClient() (*mountmanager.Client, error) {
+	d.mountClientMutex.Lock()
+	defer d.mountClientMutex.Unlock()
+	if d.mountClient == nil {
+		client, err := mountmanager.NewClient(d.mountEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		d.mountClient = client
+	}
+	return d.mountClient, nil
+}
+
 func (d *SeaweedFsDriver) AdjustedUrl(location *filer_pb.Location) string {
 	return location.Url
 }
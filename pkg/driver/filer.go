@@ -0,0 +1,101 @@
+package driver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"github.com/seaweedfs/seaweedfs/weed/util"
+)
+
+// errEntryNotFound is returned by lookupEntry when the filer has no entry at
+// the requested path.
+var errEntryNotFound = errors.New("entry not found")
+
+// splitVolumeId resolves a volume ID into the filer directory holding the
+// volume and the volume's own directory name, within the volume's cluster.
+// d talks to that cluster.
+func (d *SeaweedFsDriver) splitVolumeId(ctx context.Context, volumeId string) (parentDir, volumeName string, err error) {
+	_, volumeId, _ = parseVolumeId(volumeId)
+	if path.IsAbs(volumeId) {
+		return path.Dir(volumeId), path.Base(volumeId), nil
+	}
+	// Backward-compatibility with legacy volume ID
+	bucketsDir, err := d.bucketsDir(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	return bucketsDir, volumeId, nil
+}
+
+// volumeIdName returns the name of the volume directory of a volume ID,
+// which unlike its parent directory does not depend on the cluster.
+func volumeIdName(volumeId string) string {
+	_, volumePath, _ := parseVolumeId(volumeId)
+	return path.Base(volumePath)
+}
+
+func lookupEntry(ctx context.Context, client filer_pb.FilerClient, dir, name string) (*filer_pb.Entry, error) {
+	var entry *filer_pb.Entry
+	err := client.WithFilerClient(false, func(c filer_pb.SeaweedFilerClient) error {
+		resp, err := c.LookupDirectoryEntry(ctx, &filer_pb.LookupDirectoryEntryRequest{
+			Directory: dir,
+			Name:      name,
+		})
+		if err != nil {
+			if strings.Contains(err.Error(), filer_pb.ErrNotFound.Error()) {
+				return errEntryNotFound
+			}
+			return err
+		}
+		if resp.Entry == nil {
+			return errEntryNotFound
+		}
+		entry = resp.Entry
+		return nil
+	})
+	return entry, err
+}
+
+func createEntry(ctx context.Context, client filer_pb.FilerClient, dir string, entry *filer_pb.Entry) error {
+	return client.WithFilerClient(false, func(c filer_pb.SeaweedFilerClient) error {
+		resp, err := c.CreateEntry(ctx, &filer_pb.CreateEntryRequest{
+			Directory: dir,
+			Entry:     entry,
+		})
+		if err != nil {
+			return err
+		}
+		if resp.Error != "" {
+			return fmt.Errorf("create entry %s: %s", path.Join(dir, entry.Name), resp.Error)
+		}
+		return nil
+	})
+}
+
+func updateEntry(ctx context.Context, client filer_pb.FilerClient, dir string, entry *filer_pb.Entry) error {
+	return client.WithFilerClient(false, func(c filer_pb.SeaweedFilerClient) error {
+		_, err := c.UpdateEntry(ctx, &filer_pb.UpdateEntryRequest{
+			Directory: dir,
+			Entry:     entry,
+		})
+		return err
+	})
+}
+
+// listEntries returns every entry of dir. The listing is fully buffered so
+// callers are free to issue further filer requests per entry.
+func listEntries(ctx context.Context, client filer_pb.FilerClient, dir string) ([]*filer_pb.Entry, error) {
+	var entries []*filer_pb.Entry
+	err := filer_pb.ReadDirAllEntries(ctx, client, util.FullPath(dir), "", func(entry *filer_pb.Entry, isLast bool) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil && strings.Contains(err.Error(), filer_pb.ErrNotFound.Error()) {
+		return nil, nil
+	}
+	return entries, err
+}
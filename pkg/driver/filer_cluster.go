@@ -0,0 +1,162 @@
+package driver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb"
+	"github.com/seaweedfs/seaweedfs/weed/security"
+	"github.com/seaweedfs/seaweedfs/weed/util"
+	"google.golang.org/grpc"
+)
+
+// Parameters selecting the SeaweedFS cluster a volume is provisioned in,
+// instead of the one given by -filer.
+const (
+	filerParam = "filer"
+	// filerTlsConfigParam names the security.toml section holding the gRPC
+	// client certificate for the cluster, like the default "grpc.client"
+	filerTlsConfigParam = "filerTlsConfig"
+)
+
+// volumeIdScheme prefixes the IDs of volumes stored in another cluster than
+// the driver's: seaweedfs://[<tls section>@]<filer>[,<filer>...]/<path>.
+// Volumes of the driver's cluster keep plain filer paths as IDs.
+const volumeIdScheme = "seaweedfs://"
+
+// filerCluster identifies the cluster of a volume. The zero value is the
+// driver's own cluster.
+type filerCluster struct {
+	// filers is the comma-separated filer address list
+	filers string
+	// tlsConfig is the security.toml section of the gRPC client TLS settings
+	tlsConfig string
+}
+
+func clusterFromParams(params map[string]string) filerCluster {
+	return filerCluster{
+		filers:    params[filerParam],
+		tlsConfig: params[filerTlsConfigParam],
+	}
+}
+
+// checkVolumeCluster checks that the cluster parameters of a volume can be
+// carried by its ID: the filers of the driver are not part of it, so a TLS
+// configuration needs the filers it applies to.
+func checkVolumeCluster(params map[string]string) error {
+	if params[filerTlsConfigParam] != "" && params[filerParam] == "" {
+		return fmt.Errorf("parameter %s requires %s", filerTlsConfigParam, filerParam)
+	}
+	return nil
+}
+
+func (c filerCluster) isDefault() bool {
+	return c == filerCluster{}
+}
+
+// volumeId returns the ID of the volume at volumePath in the cluster.
+func (c filerCluster) volumeId(volumePath string) string {
+	if c.isDefault() {
+		return volumePath
+	}
+	authority := c.filers
+	if c.tlsConfig != "" {
+		authority = c.tlsConfig + "@" + authority
+	}
+	return volumeIdScheme + authority + volumePath
+}
+
+// parseVolumeId splits a volume ID into its cluster and the volume's filer
+// path, or the legacy volume name for IDs that are not paths.
+func parseVolumeId(volumeId string) (filerCluster, string, error) {
+	rest, ok := strings.CutPrefix(volumeId, volumeIdScheme)
+	if !ok {
+		return filerCluster{}, volumeId, nil
+	}
+	slash := strings.Index(rest, "/")
+	if slash <= 0 || slash == len(rest)-1 {
+		return filerCluster{}, "", fmt.Errorf("invalid volume id %q", volumeId)
+	}
+	cluster := filerCluster{filers: rest[:slash]}
+	if tlsConfig, filers, ok := strings.Cut(cluster.filers, "@"); ok {
+		cluster = filerCluster{filers: filers, tlsConfig: tlsConfig}
+	}
+	if cluster.filers == "" {
+		return filerCluster{}, "", fmt.Errorf("invalid volume id %q: no filer", volumeId)
+	}
+	return cluster, rest[slash:], nil
+}
+
+// loadClusterTLS returns the gRPC dial option of the client certificate in
+// the security.toml section of a cluster.
+var loadClusterTLS = func(section string) (grpc.DialOption, error) {
+	if util.GetViper().GetString(section+".cert") == "" {
+		return nil, fmt.Errorf("%s %q: no client certificate configured in security.toml", filerTlsConfigParam, section)
+	}
+	return security.LoadClientTLS(util.GetViper(), section), nil
+}
+
+// forCluster returns a driver talking to the filers of cluster. Drivers of
+// other clusters are created on first use and shared afterwards, along with
+// the connections of those with a TLS configuration of their own.
+func (d *SeaweedFsDriver) forCluster(cluster filerCluster) (*SeaweedFsDriver, error) {
+	if cluster.isDefault() {
+		return d, nil
+	}
+	if existing, ok := d.clusters.Load(cluster); ok {
+		return existing.(*SeaweedFsDriver), nil
+	}
+
+	filers := d.filers
+	if cluster.filers != "" {
+		filers = pb.ServerAddresses(cluster.filers).ToAddresses()
+		if len(filers) == 0 {
+			return nil, fmt.Errorf("invalid %s %q", filerParam, cluster.filers)
+		}
+	}
+	clusterDriver := d.withFilers(filers, d.grpcDialOption)
+	if cluster.tlsConfig != "" {
+		grpcDialOption, err := loadClusterTLS(cluster.tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		clusterDriver.grpcDialOption = grpcDialOption
+		clusterDriver.grpcConnections = newGrpcConnections(grpcDialOption, true)
+		if clusterDriver.filerHttp, err = loadFilerHttpClient(cluster.tlsConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, _ := d.clusters.LoadOrStore(cluster, clusterDriver)
+	return existing.(*SeaweedFsDriver), nil
+}
+
+// withFilers returns a driver like d talking to filers with grpcDialOption.
+func (d *SeaweedFsDriver) withFilers(filers []pb.ServerAddress, grpcDialOption grpc.DialOption) *SeaweedFsDriver {
+	return &SeaweedFsDriver{
+		name:                d.name,
+		nodeID:              d.nodeID,
+		version:             d.version,
+		vcap:                d.vcap,
+		cscap:               d.cscap,
+		filers:              filers,
+		grpcDialOption:      grpcDialOption,
+		signature:           d.signature,
+		filerSigningKey:     d.filerSigningKey,
+		filerReadSigningKey: d.filerReadSigningKey,
+		filerHttp:           d.filerHttp,
+		DataCenter:          d.DataCenter,
+		Rack:                d.Rack,
+		DataLocality:        d.DataLocality,
+		VolumeParentDirs:    d.VolumeParentDirs,
+	}
+}
+
+// forVolume returns a driver talking to the cluster of the volume volumeId.
+func (d *SeaweedFsDriver) forVolume(volumeId string) (*SeaweedFsDriver, error) {
+	cluster, _, err := parseVolumeId(volumeId)
+	if err != nil {
+		return nil, err
+	}
+	return d.forCluster(cluster)
+}
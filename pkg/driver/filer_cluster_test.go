@@ -0,0 +1,131 @@
+package driver
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
+	"github.com/seaweedfs/seaweedfs/weed/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestVolumeIdRoundTrip(t *testing.T) {
+	tests := []struct {
+		cluster filerCluster
+		id      string
+	}{
+		{filerCluster{}, "/buckets/pvc-1"},
+		{filerCluster{filers: "filer-b:8888"}, "seaweedfs://filer-b:8888/buckets/pvc-1"},
+		{filerCluster{filers: "b1:8888,b2:8888", tlsConfig: "grpc.cluster_b"}, "seaweedfs://grpc.cluster_b@b1:8888,b2:8888/buckets/pvc-1"},
+	}
+	for _, tt := range tests {
+		id := tt.cluster.volumeId("/buckets/pvc-1")
+		if id != tt.id {
+			t.Fatalf("volumeId = %q, want %q", id, tt.id)
+		}
+		cluster, volumePath, err := parseVolumeId(id)
+		if err != nil {
+			t.Fatalf("parseVolumeId(%q): %v", id, err)
+		}
+		if cluster != tt.cluster || volumePath != "/buckets/pvc-1" {
+			t.Fatalf("parseVolumeId(%q) = %+v, %q", id, cluster, volumePath)
+		}
+		if parentDir, volumeName, err := (&SeaweedFsDriver{}).splitVolumeId(context.Background(), id); err != nil || parentDir != "/buckets" || volumeName != "pvc-1" {
+			t.Fatalf("splitVolumeId(%q) = %q, %q, %v", id, parentDir, volumeName, err)
+		}
+	}
+}
+
+func TestParseVolumeIdRejectsMalformed(t *testing.T) {
+	for _, id := range []string{"seaweedfs://", "seaweedfs://filer-b:8888", "seaweedfs:///buckets/pvc-1", "seaweedfs://tls@/buckets/pvc-1", "seaweedfs://filer-b:8888/"} {
+		if _, _, err := parseVolumeId(id); err == nil {
+			t.Fatalf("parseVolumeId(%q) succeeded, want error", id)
+		}
+	}
+}
+
+func TestForClusterReusesDrivers(t *testing.T) {
+	d := &SeaweedFsDriver{name: "test"}
+	if got, _ := d.forCluster(filerCluster{}); got != d {
+		t.Fatalf("forCluster of the default cluster returned another driver")
+	}
+
+	cluster := filerCluster{filers: "b1:8888,b2:8888"}
+	first, err := d.forCluster(cluster)
+	if err != nil {
+		t.Fatalf("forCluster: %v", err)
+	}
+	if len(first.filers) != 2 || first.name != "test" {
+		t.Fatalf("unexpected cluster driver: filers %v, name %q", first.filers, first.name)
+	}
+	second, _ := d.forVolume(cluster.volumeId("/buckets/pvc-1"))
+	if second != first {
+		t.Fatalf("forVolume did not reuse the cluster driver")
+	}
+
+	if _, err := d.forCluster(filerCluster{filers: "b1:8888", tlsConfig: "grpc.missing"}); err == nil {
+		t.Fatalf("forCluster accepted a TLS section without certificate")
+	}
+}
+
+func TestBuildMountArgsOtherCluster(t *testing.T) {
+	mounter := &mountServiceMounter{
+		driver:     &SeaweedFsDriver{},
+		volumeID:   "seaweedfs://filer-b:8888/buckets/pvc-1",
+		volContext: map[string]string{filerParam: "filer-b:8888", filerTlsConfigParam: "grpc.cluster_b"},
+	}
+
+	args, err := mounter.buildMountArgs("/staging", "/cache", "/socket", []string{"filer-a:8888"})
+	if err != nil {
+		t.Fatalf("buildMountArgs: %v", err)
+	}
+	for _, arg := range []string{"-filer=filer-b:8888", "-filer.path=/buckets/pvc-1", "-collection=pvc-1"} {
+		if !slices.Contains(args, arg) {
+			t.Fatalf("mount args do not contain %s: %v", arg, args)
+		}
+	}
+}
+
+func TestClusterDriversDialWithTheirTLSConfig(t *testing.T) {
+	address, serverCert, clients := startTLSServer(t)
+	cert, key := testKeyPair(t, "cluster-b")
+	loadDefault := loadClusterTLS
+	t.Cleanup(func() { loadClusterTLS = loadDefault })
+	loadClusterTLS = func(section string) (grpc.DialOption, error) {
+		tlsConfig, err := clientTLSConfig(&mountmanager.MountCredentials{TLSCert: cert, TLSKey: key, CACert: serverCert})
+		if err != nil {
+			return nil, err
+		}
+		return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+	}
+
+	d := &SeaweedFsDriver{name: "test", filers: []pb.ServerAddress{"filer:8888"}}
+	clusterDriver, err := d.forCluster(filerCluster{filers: "filer-b:8888", tlsConfig: "grpc.cluster_b"})
+	if err != nil {
+		t.Fatalf("forCluster: %v", err)
+	}
+	for range 2 {
+		err := clusterDriver.withGrpcClient(context.Background(), false, address, func(conn *grpc.ClientConn) error {
+			_, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("call to cluster b: %v", err)
+		}
+	}
+	if got := clients(); !slices.Equal(got, []string{"cluster-b", "cluster-b"}) {
+		t.Fatalf("calls were made as %v, want cluster-b", got)
+	}
+	if len(clusterDriver.grpcConnections.conns) != 1 {
+		t.Fatalf("cluster driver kept %d connections, want 1", len(clusterDriver.grpcConnections.conns))
+	}
+
+	// request secrets without a certificate keep the cluster's connections
+	requestDriver, err := d.forRequest(filerCluster{filers: "filer-b:8888", tlsConfig: "grpc.cluster_b"}, map[string]string{secretFilerSigningKey: "k"})
+	if err != nil || requestDriver.grpcConnections != clusterDriver.grpcConnections {
+		t.Fatalf("forRequest = %+v, %v, want the connections of the cluster", requestDriver, err)
+	}
+}
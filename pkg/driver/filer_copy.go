@@ -0,0 +1,259 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"github.com/seaweedfs/seaweedfs/weed/security"
+	"github.com/seaweedfs/seaweedfs/weed/util"
+)
+
+// backgroundCopy is the copy of a volume into a snapshot or of a content
+// source into a new volume, running in the background of the request that
+// started it.
+type backgroundCopy struct {
+	cancel context.CancelFunc
+	// stopped is closed once the copy no longer writes to its target
+	stopped chan struct{}
+	// err is set, with the lock of the target held, when the copy failed.
+	// A copy that succeeded is no longer tracked.
+	err error
+}
+
+// backgroundCopies tracks the running and failed copies by the ID of their
+// target. Callers hold the lock of the target.
+type backgroundCopies struct {
+	mu     sync.Mutex
+	copies map[string]*backgroundCopy
+}
+
+func (c *backgroundCopies) get(id string) *backgroundCopy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.copies[id]
+}
+
+func (c *backgroundCopies) add(id string, running *backgroundCopy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.copies == nil {
+		c.copies = make(map[string]*backgroundCopy)
+	}
+	c.copies[id] = running
+}
+
+func (c *backgroundCopies) remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.copies, id)
+}
+
+// stop cancels the copy to id, if any, and waits until it no longer writes
+// to its target.
+func (c *backgroundCopies) stop(id string) {
+	if running := c.get(id); running != nil {
+		running.cancel()
+		<-running.stopped
+		c.remove(id)
+	}
+}
+
+// csiExtendedPrefix marks the Extended attributes owned by the driver. They
+// describe the directory they are stored on and are never copied along with
+// its content.
+const csiExtendedPrefix = "csi."
+
+// treeCopier duplicates a filer directory tree. File content is streamed
+// through the filer's HTTP API instead of copying chunk references: the filer
+// deletes a file's chunks when that file is overwritten or removed, so sharing
+// chunks between a volume and its snapshot would silently corrupt the copy.
+type treeCopier struct {
+	driver *SeaweedFsDriver
+
+	// storage options for the copied file content
+	collection  string
+	replication string
+	diskType    string
+
+	// copiedBytes is the total size of the copied files
+	copiedBytes int64
+}
+
+func newTreeCopier(driver *SeaweedFsDriver, params map[string]string) *treeCopier {
+	return &treeCopier{
+		driver:      driver,
+		collection:  params["collection"],
+		replication: params["replication"],
+		diskType:    params["diskType"],
+	}
+}
+
+// copyDir copies the content of the directory srcDir into the existing
+// directory dstDir.
+func (c *treeCopier) copyDir(ctx context.Context, srcDir, dstDir string) error {
+	entries, err := listEntries(ctx, c.driver, srcDir)
+	if err != nil {
+		return fmt.Errorf("list %s: %w", srcDir, err)
+	}
+
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDirectory:
+			if err := createEntry(ctx, c.driver, dstDir, cloneEntryMetadata(entry)); err != nil {
+				return err
+			}
+			if err := c.copyDir(ctx, path.Join(srcDir, entry.Name), path.Join(dstDir, entry.Name)); err != nil {
+				return err
+			}
+		case len(entry.Chunks) == 0:
+			// empty files, symlinks and small files with inlined content
+			// carry no chunks and can be recreated from metadata alone
+			file := cloneEntryMetadata(entry)
+			file.Content = entry.Content
+			if err := createEntry(ctx, c.driver, dstDir, file); err != nil {
+				return err
+			}
+			c.copiedBytes += int64(len(entry.Content))
+		default:
+			if err := c.copyFile(ctx, srcDir, dstDir, entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyFile streams the content of a chunked file to its new location, then
+// restores the source attributes on the newly written entry.
+func (c *treeCopier) copyFile(ctx context.Context, srcDir, dstDir string, entry *filer_pb.Entry) error {
+	srcPath := path.Join(srcDir, entry.Name)
+	dstPath := path.Join(dstDir, entry.Name)
+	glog.V(4).Infof("copying %s to %s", srcPath, dstPath)
+
+	filerAddress := c.driver.filers[c.driver.filerIndex].ToHttpAddress()
+
+	getReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.driver.filerHttp.url(filerAddress, srcPath, nil), nil)
+	if err != nil {
+		return err
+	}
+	setFilerJwt(getReq, "jwt.filer_signing.read", c.driver.filerReadSigningKey)
+	getResp, err := c.driver.filerHttp.client.Do(getReq)
+	if err != nil {
+		return fmt.Errorf("read %s: %w", srcPath, err)
+	}
+	defer getResp.Body.Close()
+	if getResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("read %s: %s", srcPath, getResp.Status)
+	}
+
+	query := url.Values{}
+	if c.collection != "" {
+		query.Set("collection", c.collection)
+	}
+	if c.replication != "" {
+		query.Set("replication", c.replication)
+	}
+	if c.diskType != "" {
+		query.Set("disk", c.diskType)
+	}
+	putReq, err := http.NewRequestWithContext(ctx, http.MethodPut, c.driver.filerHttp.url(filerAddress, dstPath, query), getResp.Body)
+	if err != nil {
+		return err
+	}
+	putReq.ContentLength = getResp.ContentLength
+	if mime := entry.GetAttributes().GetMime(); mime != "" {
+		putReq.Header.Set("Content-Type", mime)
+	}
+	setFilerJwt(putReq, "jwt.filer_signing", c.driver.filerSigningKey)
+	putResp, err := c.driver.filerHttp.client.Do(putReq)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", dstPath, err)
+	}
+	_, _ = io.Copy(io.Discard, putResp.Body)
+	putResp.Body.Close()
+	if putResp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("write %s: %s", dstPath, putResp.Status)
+	}
+
+	written, err := lookupEntry(ctx, c.driver, dstDir, entry.Name)
+	if err != nil {
+		return fmt.Errorf("lookup %s: %w", dstPath, err)
+	}
+	restored := cloneEntryMetadata(entry)
+	restored.Chunks = written.Chunks
+	if restored.Attributes != nil && written.Attributes != nil {
+		restored.Attributes.FileSize = written.Attributes.FileSize
+		restored.Attributes.Md5 = written.Attributes.Md5
+	}
+	if err := updateEntry(ctx, c.driver, dstDir, restored); err != nil {
+		return fmt.Errorf("restore attributes of %s: %w", dstPath, err)
+	}
+
+	c.copiedBytes += int64(filerEntrySize(written))
+	return nil
+}
+
+// cloneEntryMetadata returns a copy of entry without its content and without
+// the Extended attributes owned by the driver.
+func cloneEntryMetadata(entry *filer_pb.Entry) *filer_pb.Entry {
+	clone := &filer_pb.Entry{
+		Name:        entry.Name,
+		IsDirectory: entry.IsDirectory,
+	}
+	if entry.Attributes != nil {
+		attributes := *entry.Attributes
+		attributes.Inode = 0
+		clone.Attributes = &attributes
+	}
+	for key, value := range entry.Extended {
+		if strings.HasPrefix(key, csiExtendedPrefix) {
+			continue
+		}
+		if clone.Extended == nil {
+			clone.Extended = make(map[string][]byte)
+		}
+		clone.Extended[key] = value
+	}
+	return clone
+}
+
+func filerEntrySize(entry *filer_pb.Entry) uint64 {
+	size := entry.GetAttributes().GetFileSize()
+	for _, chunk := range entry.GetChunks() {
+		if end := uint64(chunk.Offset) + chunk.Size; end > size {
+			size = end
+		}
+	}
+	return size
+}
+
+// setFilerJwt signs a filer HTTP request with key, or else the key
+// configured under configKey in security.toml, if any.
+func setFilerJwt(req *http.Request, configKey, key string) {
+	v := util.GetViper()
+	if key == "" {
+		key = v.GetString(configKey + ".key")
+	}
+	if key == "" {
+		return
+	}
+	expiresAfterSec := v.GetInt(configKey + ".expires_after_seconds")
+	if expiresAfterSec == 0 {
+		expiresAfterSec = 10
+	}
+	jwt := security.GenJwtForFilerServer(security.SigningKey(key), expiresAfterSec)
+	req.Header.Set("Authorization", "BEARER "+string(jwt))
+}
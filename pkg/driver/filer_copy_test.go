@@ -0,0 +1,32 @@
+package driver
+
+import (
+	"context"
+	"testing"
+)
+
+// TestBackgroundCopiesStopWaitsForCopy verifies that stopping a background copy
+// cancels it and returns only once the copy no longer writes.
+func TestBackgroundCopiesStopWaitsForCopy(t *testing.T) {
+	var copies backgroundCopies
+	ctx, cancel := context.WithCancel(context.Background())
+	running := &backgroundCopy{cancel: cancel, stopped: make(chan struct{})}
+	copies.add("/.snapshots/snapshot-1", running)
+
+	writing := true
+	go func() {
+		<-ctx.Done()
+		writing = false
+		close(running.stopped)
+	}()
+
+	copies.stop("/.snapshots/snapshot-1")
+	if writing {
+		t.Fatal("stop returned while the copy was still writing")
+	}
+	if copies.get("/.snapshots/snapshot-1") != nil {
+		t.Fatal("stopped copy is still tracked")
+	}
+	// stopping an unknown copy is a no-op
+	copies.stop("/.snapshots/snapshot-2")
+}
@@ -0,0 +1,88 @@
+package driver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/seaweedfs/seaweedfs/weed/util"
+)
+
+// filerHttpClient sends requests to the HTTP API of the filers, over HTTPS
+// when security.toml enables https.client like it does for weed itself.
+type filerHttpClient struct {
+	scheme string
+	// tlsConfig is nil for plain HTTP
+	tlsConfig *tls.Config
+	client    *http.Client
+}
+
+func newFilerHttpClient(tlsConfig *tls.Config) *filerHttpClient {
+	if tlsConfig == nil {
+		return &filerHttpClient{scheme: "http", client: &http.Client{}}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &filerHttpClient{
+		scheme:    "https",
+		tlsConfig: tlsConfig,
+		client:    &http.Client{Transport: transport},
+	}
+}
+
+// loadFilerHttpClient returns the filer HTTP client configured in
+// security.toml, presenting the client certificate of section, such as
+// "https.client" or the section of a cluster's filerTlsConfig.
+func loadFilerHttpClient(section string) (*filerHttpClient, error) {
+	v := util.GetViper()
+	if !v.GetBool("https.client.enabled") {
+		return newFilerHttpClient(nil), nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: v.GetBool("https.client.insecure_skip_verify")}
+	if certFile := v.GetString(section + ".cert"); certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, v.GetString(section+".key"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", section, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if caFile := v.GetString(section + ".ca"); caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", section, err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("%s: no certificate in %s", section, caFile)
+		}
+	}
+	return newFilerHttpClient(tlsConfig), nil
+}
+
+// withClientTLS returns a client like c presenting the client certificate
+// of clientConfig instead, and trusting its CAs if it has any. Plain HTTP
+// clients are returned as is.
+func (c *filerHttpClient) withClientTLS(clientConfig *tls.Config) *filerHttpClient {
+	if c.tlsConfig == nil {
+		return c
+	}
+	tlsConfig := c.tlsConfig.Clone()
+	tlsConfig.Certificates = clientConfig.Certificates
+	if clientConfig.RootCAs != nil {
+		tlsConfig.RootCAs = clientConfig.RootCAs
+	}
+	return newFilerHttpClient(tlsConfig)
+}
+
+func (c *filerHttpClient) url(address, filePath string, query url.Values) string {
+	u := url.URL{
+		Scheme:   c.scheme,
+		Host:     address,
+		Path:     filePath,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
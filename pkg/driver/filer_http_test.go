@@ -0,0 +1,56 @@
+package driver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb"
+)
+
+func TestFilerHttpClientUrl(t *testing.T) {
+	if got := newFilerHttpClient(nil).url("filer:8888", "/buckets/pvc-1/a b", nil); got != "http://filer:8888/buckets/pvc-1/a%20b" {
+		t.Fatalf("plain url = %s", got)
+	}
+	if got := newFilerHttpClient(&tls.Config{}).url("filer:8888", "/f", map[string][]string{"collection": {"c"}}); got != "https://filer:8888/f?collection=c" {
+		t.Fatalf("https url = %s", got)
+	}
+}
+
+func TestRequestDriversPresentTheirCertificateToHttpsFilers(t *testing.T) {
+	var clients []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clients = append(clients, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// the node trusts the filer but has no client certificate of its own
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	d := &SeaweedFsDriver{name: "test", filers: []pb.ServerAddress{"filer:8888"}, filerHttp: newFilerHttpClient(&tls.Config{RootCAs: rootCAs})}
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	for _, tenant := range []string{"tenant-a", "tenant-b"} {
+		cert, key := testKeyPair(t, tenant)
+		requestDriver, err := d.forRequest(filerCluster{}, map[string]string{secretTLSCert: cert, secretTLSKey: key})
+		if err != nil {
+			t.Fatalf("forRequest: %v", err)
+		}
+		resp, err := requestDriver.filerHttp.client.Get(requestDriver.filerHttp.url(address, "/f", nil))
+		if err != nil {
+			t.Fatalf("request as %s: %v", tenant, err)
+		}
+		resp.Body.Close()
+	}
+	if strings.Join(clients, ",") != "tenant-a,tenant-b" {
+		t.Fatalf("requests were made as %v", clients)
+	}
+	if _, err := d.filerHttp.client.Get(d.filerHttp.url(address, "/f", nil)); err == nil {
+		t.Fatalf("request without a client certificate succeeded")
+	}
+}
@@ -0,0 +1,54 @@
+package driver
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// grpcConnections are the gRPC connections of a driver dialing with other
+// credentials than the node's. pb.WithGrpcClient shares its connections by
+// address alone, so a connection dialed with one set of credentials would be
+// reused for calls that should carry another.
+type grpcConnections struct {
+	dialOption grpc.DialOption
+	// shared connections are kept for the lifetime of the driver, the others
+	// are dialed for a single call and closed after it
+	shared bool
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+func newGrpcConnections(dialOption grpc.DialOption, shared bool) *grpcConnections {
+	return &grpcConnections{
+		dialOption: dialOption,
+		shared:     shared,
+		conns:      make(map[string]*grpc.ClientConn),
+	}
+}
+
+// with runs fn with a connection to address.
+func (c *grpcConnections) with(address string, fn func(*grpc.ClientConn) error) error {
+	if !c.shared {
+		conn, err := grpc.NewClient(address, c.dialOption)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return fn(conn)
+	}
+
+	c.mu.Lock()
+	conn, ok := c.conns[address]
+	if !ok {
+		var err error
+		if conn, err = grpc.NewClient(address, c.dialOption); err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		c.conns[address] = conn
+	}
+	c.mu.Unlock()
+	return fn(conn)
+}
@@ -1,9 +1,12 @@
 package driver
 
 import (
+	"context"
+	"errors"
 	"runtime/debug"
 	"time"
 
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
 	"github.com/seaweedfs/seaweedfs/weed/glog"
 	"k8s.io/mount-utils"
 )
@@ -14,6 +17,9 @@ const (
 	// frozen FUSE daemon (os.ReadDir blocked in the kernel) cannot stall
 	// the health monitor goroutine indefinitely.
 	defaultHealthCheckTimeout = 5 * time.Second
+	// mountEventsRetryInterval is how long the event watcher waits before
+	// following the events of the mount service again after losing them
+	mountEventsRetryInterval = 10 * time.Second
 )
 
 func (ns *NodeServer) startHealthMonitor(interval time.Duration) {
@@ -33,6 +39,89 @@ func (ns *NodeServer) startHealthMonitor(interval time.Duration) {
 	}()
 }
 
+// watchMountEvents makes the health monitor check a volume as soon as the
+// mount service reports that its weed mount process crashed or was
+// restarted, instead of on the next tick. Mount services without the event
+// stream are left to the ticks.
+func (ns *NodeServer) watchMountEvents() {
+	go func() {
+		unsupported := false
+		for {
+			err := ns.followMountEvents()
+			switch {
+			case errors.Is(err, mountmanager.ErrEventsUnsupported):
+				// asked again after the retry interval in case the mount
+				// service gets upgraded, logged once
+				if !unsupported {
+					glog.Infof("health monitor: the mount service does not stream events, relying on health checks every tick")
+				}
+				unsupported = true
+			case err != nil:
+				unsupported = false
+				glog.Warningf("health monitor: lost the events of the mount service: %v", err)
+			}
+			select {
+			case <-time.After(mountEventsRetryInterval):
+			case <-ns.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// followMountEvents handles the events of the mount service until the node
+// server stops or the stream fails.
+func (ns *NodeServer) followMountEvents() error {
+	client, err := ns.Driver.mountServiceClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-ns.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	events, err := client.WatchEvents(ctx, "")
+	if err != nil {
+		return err
+	}
+	glog.Infof("health monitor: following the events of the mount service")
+	// events missed while not following are caught up on by the next tick
+	for {
+		event, err := events.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		ns.handleMountEvent(event)
+	}
+}
+
+// handleMountEvent checks the volume of event right away if its weed mount
+// process crashed without the mount service restarting it, or was
+// restarted. Starts and requested stops are the node server's own doing.
+func (ns *NodeServer) handleMountEvent(event *mountmanager.MountEvent) {
+	switch {
+	case event.Type == mountmanager.EventRestarted:
+	case event.Type == mountmanager.EventExited && !event.Stopped && !event.Restarting:
+	default:
+		return
+	}
+	if _, ok := ns.volumes.Load(event.VolumeID); !ok {
+		return
+	}
+	glog.Infof("health monitor: weed mount process of volume %s %s, checking it", event.VolumeID, event.Type)
+	ns.launchVolumeHealthCheck(event.VolumeID)
+}
+
 // runHealthCheckTick runs one health check pass with panic recovery so
 // that an unexpected crash inside checkAndRecoverVolumes or recoverVolume
 // does not silently disable self-healing for the lifetime of the pod.
@@ -149,6 +238,15 @@ func (ns *NodeServer) performVolumeHealthCheck(volumeID string) {
 		return
 	}
 
+	// The mount service restarts a crashed weed mount in place: staging
+	// is healthy again, but on a new FUSE mount the publish binds and the
+	// containers' mounts do not see.
+	if oldDevice := replacedStagingDevice(vol); oldDevice != "" {
+		glog.Warningf("health monitor: staging mount of volume %s was replaced by a restarted weed mount", volumeID)
+		ns.repairRestartedMount(volumeID, oldDevice)
+		return
+	}
+
 	// Staging is alive; check whether any publish bind mounts have
 	// been dropped (e.g. from a previous partial recovery) and need
 	// to be re-bound without tearing down the FUSE mount.
@@ -240,6 +338,72 @@ func (ns *NodeServer) retryPublishPaths(volumeID string) {
 	})
 }
 
+// replacedStagingDevice returns the device the staging mount of vol had
+// when it was staged if a different FUSE mount has taken its place since,
+// or "" if it has not or the device is unknown.
+func replacedStagingDevice(vol *Volume) string {
+	if vol.stagedDevice == "" {
+		return ""
+	}
+	device, err := getMountDevice(vol.StagedPath)
+	if err != nil || device == "" || device == vol.stagedDevice {
+		return ""
+	}
+	return vol.stagedDevice
+}
+
+// repairRestartedMount re-binds the publish paths of a volume whose weed
+// mount the mount service restarted, and replaces the mounts of the old
+// FUSE device inside pod containers. Unlike recoverVolume it leaves the
+// staging mount alone, since it is healthy.
+func (ns *NodeServer) repairRestartedMount(volumeID, oldDevice string) {
+	volumeMutex := ns.getVolumeMutex(volumeID)
+	volumeMutex.Lock()
+	defer volumeMutex.Unlock()
+
+	val, ok := ns.volumes.Load(volumeID)
+	if !ok {
+		return
+	}
+	vol := val.(*Volume)
+	if vol.stagedDevice != oldDevice {
+		// repaired or re-staged in the meantime
+		return
+	}
+	newDevice, err := getMountDevice(vol.StagedPath)
+	if err != nil {
+		glog.Warningf("health monitor: cannot read device of staging mount of volume %s: %v", volumeID, err)
+		return
+	}
+
+	failed := 0
+	vol.publishPaths.Range(func(k, v interface{}) bool {
+		path := k.(string)
+		readOnly := v.(bool)
+		glog.Infof("health monitor: re-binding publish path %s for volume %s to the restarted mount", path, volumeID)
+		if !ns.tearDownStalePublishBind(path, volumeID) {
+			failed++
+			return true
+		}
+		if err := vol.Publish(vol.StagedPath, path, readOnly); err != nil {
+			glog.Errorf("health monitor: failed to re-bind publish path %s for volume %s: %v", path, volumeID, err)
+			failed++
+			return true
+		}
+		remountInContainers(path, vol.StagedPath, oldDevice, readOnly)
+		return true
+	})
+
+	// Paths that failed are unhealthy binds now, which retryPublishPaths
+	// picks up on the next sweep
+	vol.stagedDevice = newDevice
+	if failed > 0 {
+		glog.Warningf("health monitor: volume %s repaired after weed mount restart with %d publish path failure(s)", volumeID, failed)
+		return
+	}
+	glog.Infof("health monitor: volume %s repaired after weed mount restart", volumeID)
+}
+
 func (ns *NodeServer) recoverVolume(volumeID string) {
 	volumeMutex := ns.getVolumeMutex(volumeID)
 	volumeMutex.Lock()
@@ -330,7 +494,7 @@ func (ns *NodeServer) recoverVolume(volumeID string) {
 	}
 
 	// Step 3: Re-stage with a fresh FUSE mount.
-	newVol, err := ns.stageNewVolume(volumeID, stagingPath, vol.volContext, vol.readOnly)
+	newVol, err := ns.stageNewVolume(volumeID, stagingPath, vol.volContext, vol.readOnly, vol.credentials)
 	if err != nil {
 		glog.Errorf("health monitor: failed to re-stage volume %s: %v", volumeID, err)
 		return
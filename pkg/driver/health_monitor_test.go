@@ -10,6 +10,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
 )
 
 // fakeMountState tracks the behavior of a fake FUSE mount across a
@@ -102,10 +104,10 @@ func newNodeServerWithFakes(t *testing.T, state *fakeMountState) *NodeServer {
 		Driver:        &SeaweedFsDriver{},
 		volumeMutexes: NewKeyMutex(),
 		stopCh:        make(chan struct{}),
-		mounterFactory: func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string) (Mounter, error) {
+		mounterFactory: func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string, credentials *mountmanager.MountCredentials) (Mounter, error) {
 			return state.newMounter(), nil
 		},
-		capacityFn: func(volumeID string) (int64, error) {
+		capacityFn: func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
 			return 0, errors.New("no capacity in tests")
 		},
 		isHealthyFn: func(path string) bool {
@@ -155,7 +157,7 @@ func TestHealthMonitorRecoversStaleMount(t *testing.T) {
 	volCtx := map[string]string{"collection": "c"}
 
 	// --- Stage ---
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume: %v", err)
 	}
@@ -260,7 +262,7 @@ func TestHealthMonitorAbortsOnUnmounterError(t *testing.T) {
 	stagingPath := filepath.Join(t.TempDir(), "staging")
 	volCtx := map[string]string{"collection": "c"}
 
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume: %v", err)
 	}
@@ -309,7 +311,7 @@ func TestHealthMonitorPreservesPublishesOnReStageFailure(t *testing.T) {
 	publishPath := filepath.Join(root, "pod", "mount")
 	volCtx := map[string]string{"collection": "c"}
 
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, volCtx, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume: %v", err)
 	}
@@ -326,7 +328,7 @@ func TestHealthMonitorPreservesPublishesOnReStageFailure(t *testing.T) {
 	// Swap in a failing factory so the recovery's stageNewVolume errors
 	// (the first factory call above already succeeded).
 	wantErr := errors.New("simulated re-stage failure")
-	ns.mounterFactory = func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string) (Mounter, error) {
+	ns.mounterFactory = func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string, credentials *mountmanager.MountCredentials) (Mounter, error) {
 		return nil, wantErr
 	}
 
@@ -366,7 +368,7 @@ func TestHealthMonitorSkipsHealthyVolumes(t *testing.T) {
 	ns := newNodeServerWithFakes(t, state)
 
 	stagingPath := filepath.Join(t.TempDir(), "staging")
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{}, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{}, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume: %v", err)
 	}
@@ -436,7 +438,7 @@ func TestHealthMonitorDeduplicatesInFlightRecovery(t *testing.T) {
 	ns.activeRecoveries.Store("vol-1", struct{}{})
 
 	stagingPath := filepath.Join(t.TempDir(), "staging")
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{}, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{}, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume: %v", err)
 	}
@@ -474,7 +476,7 @@ func TestHealthMonitorRetriesFailedPublishes(t *testing.T) {
 	stagingPath := filepath.Join(root, "staging")
 	publishPath := filepath.Join(root, "pod", "mount")
 
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{}, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{}, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume: %v", err)
 	}
@@ -511,3 +513,61 @@ func TestHealthMonitorRetriesFailedPublishes(t *testing.T) {
 		t.Errorf("expected %d bind mounts after retry, got %d", initialBind+1, state.bindMountCalls)
 	}
 }
+
+// TestReplacedStagingDeviceNeedsKnownDevices verifies that a staging
+// mount is only taken for replaced when both the recorded and the
+// current device are known, so that volumes staged without a readable
+// mountinfo are never "repaired".
+func TestReplacedStagingDeviceNeedsKnownDevices(t *testing.T) {
+	stagingPath := t.TempDir()
+
+	if got := replacedStagingDevice(&Volume{StagedPath: stagingPath}); got != "" {
+		t.Errorf("expected no replacement without a recorded device, got %q", got)
+	}
+	// t.TempDir is not a mount point, so its current device is unknown
+	if got := replacedStagingDevice(&Volume{StagedPath: stagingPath, stagedDevice: "0:4242"}); got != "" {
+		t.Errorf("expected no replacement without a current device, got %q", got)
+	}
+}
+
+// TestHandleMountEventRecoversCrashedMount verifies that the health monitor
+// recovers a volume as soon as the mount service reports a crash it does
+// not restart, and ignores the events of its own mounts and unmounts.
+func TestHandleMountEventRecoversCrashedMount(t *testing.T) {
+	state := newFakeMountState()
+	ns := newNodeServerWithFakes(t, state)
+
+	stagingPath := filepath.Join(t.TempDir(), "staging")
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{}, false, nil)
+	if err != nil {
+		t.Fatalf("stageNewVolume: %v", err)
+	}
+	vol.volContext = map[string]string{}
+	ns.volumes.Store("vol-1", vol)
+	state.healthy.Store(false)
+
+	for _, event := range []mountmanager.MountEvent{
+		{Type: mountmanager.EventStarted, VolumeID: "vol-1"},
+		{Type: mountmanager.EventExited, VolumeID: "vol-1", Stopped: true},
+		{Type: mountmanager.EventExited, VolumeID: "vol-1", Restarting: true},
+		{Type: mountmanager.EventExited, VolumeID: "vol-2"},
+	} {
+		ns.handleMountEvent(&event)
+	}
+	ns.recoveryWg.Wait()
+	state.mu.Lock()
+	stageCalls := state.stageCalls
+	state.mu.Unlock()
+	if stageCalls != 1 {
+		t.Fatalf("expected no recovery for ignored events, got %d stage calls", stageCalls)
+	}
+
+	ns.handleMountEvent(&mountmanager.MountEvent{Type: mountmanager.EventExited, VolumeID: "vol-1"})
+	ns.recoveryWg.Wait()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.stageCalls != 2 {
+		t.Errorf("expected the crash to re-stage the volume, got %d stage calls", state.stageCalls)
+	}
+}
@@ -0,0 +1,189 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// volumeMetadataKey is the Extended attribute of a volume directory holding
+// the volumeMetadata record written by CreateVolume.
+const volumeMetadataKey = csiExtendedPrefix + "volume"
+
+// Parameters added by the external-provisioner when it runs with
+// --extra-create-metadata.
+const (
+	pvcNameParam      = "csi.storage.k8s.io/pvc/name"
+	pvcNamespaceParam = "csi.storage.k8s.io/pvc/namespace"
+)
+
+// volumeMetadata is the state of a volume kept next to its data, so that
+// neither a repeated CreateVolume call nor the node server has to rely on
+// the orchestrator to learn how the volume was provisioned.
+type volumeMetadata struct {
+	CreationTime time.Time `json:"creationTime"`
+	// RequestedBytes is the capacity required by the creating request
+	RequestedBytes int64 `json:"requestedBytes"`
+	// CapacityBytes is the current capacity, raised by expansion
+	CapacityBytes int64 `json:"capacityBytes"`
+	// Parameters are the parameters of the creating request
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// PVCName and PVCNamespace identify the claim the volume was created for
+	PVCName      string `json:"pvcName,omitempty"`
+	PVCNamespace string `json:"pvcNamespace,omitempty"`
+	// ContentSource is the filer path of the snapshot or volume the volume
+	// was populated from
+	ContentSource string `json:"contentSource,omitempty"`
+	// Populating is set until the copy from ContentSource has completed
+	Populating bool `json:"populating,omitempty"`
+	// PublishedNodes are the nodes the volume is published to by
+	// ControllerPublishVolume
+	PublishedNodes []string `json:"publishedNodes,omitempty"`
+}
+
+// readVolumeMetadata returns the metadata stored on a volume directory, or
+// nil if the volume was created before metadata was recorded.
+func readVolumeMetadata(entry *filer_pb.Entry) (*volumeMetadata, error) {
+	data, ok := entry.Extended[volumeMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	meta := &volumeMetadata{}
+	if err := json.Unmarshal(data, meta); err != nil {
+		return nil, fmt.Errorf("invalid volume metadata on %s: %v", entry.Name, err)
+	}
+	return meta, nil
+}
+
+// store writes the metadata to the Extended attributes of entry.
+func (m *volumeMetadata) store(entry *filer_pb.Entry) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if entry.Extended == nil {
+		entry.Extended = make(map[string][]byte)
+	}
+	entry.Extended[volumeMetadataKey] = data
+	return nil
+}
+
+// matches reports whether a CreateVolume request for the same name asks for
+// the volume described by the metadata.
+func (m *volumeMetadata) matches(capacity int64, params map[string]string, contentSource string) bool {
+	return m.RequestedBytes == capacity &&
+		maps.Equal(m.Parameters, params) &&
+		m.ContentSource == contentSource
+}
+
+// newVolumeMetadata describes a volume created now for a request asking for
+// capacity bytes with the given parameters.
+func newVolumeMetadata(capacity int64, params map[string]string, contentSource string) *volumeMetadata {
+	return &volumeMetadata{
+		CreationTime:   time.Now().UTC(),
+		RequestedBytes: capacity,
+		CapacityBytes:  capacity,
+		Parameters:     params,
+		PVCName:        params[pvcNameParam],
+		PVCNamespace:   params[pvcNamespaceParam],
+		ContentSource:  contentSource,
+	}
+}
+
+// lookupVolumeMetadata returns the metadata of the volume volumeId, or nil if
+// the volume has none.
+func lookupVolumeMetadata(ctx context.Context, client *SeaweedFsDriver, volumeId string) (*volumeMetadata, error) {
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := lookupEntry(ctx, client, parentDir, volumeName)
+	if err != nil {
+		return nil, err
+	}
+	return readVolumeMetadata(entry)
+}
+
+// updateVolumeMetadata applies update to the metadata of the volume volumeId
+// and writes it back. Volumes without metadata get a fresh record.
+func updateVolumeMetadata(ctx context.Context, client *SeaweedFsDriver, volumeId string, update func(*volumeMetadata)) error {
+	parentDir, volumeName, err := client.splitVolumeId(ctx, volumeId)
+	if err != nil {
+		return err
+	}
+	entry, err := lookupEntry(ctx, client, parentDir, volumeName)
+	if err != nil {
+		return err
+	}
+	meta, err := readVolumeMetadata(entry)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		meta = &volumeMetadata{CreationTime: time.Now().UTC()}
+	}
+	update(meta)
+	if err := meta.store(entry); err != nil {
+		return err
+	}
+	return updateEntry(ctx, client, parentDir, entry)
+}
+
+// volumeCapacityFromFiler returns the capacity recorded in the metadata of
+// the volume volumeId.
+func volumeCapacityFromFiler(client *SeaweedFsDriver, volumeId string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	meta, err := lookupVolumeMetadata(ctx, client, volumeId)
+	if err != nil {
+		return 0, err
+	}
+	if meta == nil || meta.CapacityBytes <= 0 {
+		return 0, fmt.Errorf("no capacity recorded for volume %s", volumeId)
+	}
+	return meta.CapacityBytes, nil
+}
+
+// listedVolume is a volume found by listVolumes.
+type listedVolume struct {
+	volumeId string
+	meta     *volumeMetadata
+}
+
+// listVolumes returns the volumes stored in parentDirs. Only directories
+// carrying volume metadata are reported, so that buckets and directories not
+// created by the driver are left out.
+func listVolumes(ctx context.Context, client filer_pb.FilerClient, parentDirs []string) ([]*listedVolume, error) {
+	var volumes []*listedVolume
+	for _, parentDir := range parentDirs {
+		entries, err := listEntries(ctx, client, parentDir)
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", parentDir, err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDirectory {
+				continue
+			}
+			meta, err := readVolumeMetadata(entry)
+			if err != nil {
+				glog.Warningf("skipping volume %s: %v", path.Join(parentDir, entry.Name), err)
+				continue
+			}
+			if meta == nil {
+				continue
+			}
+			volumes = append(volumes, &listedVolume{
+				volumeId: path.Join(parentDir, entry.Name),
+				meta:     meta,
+			})
+		}
+	}
+	return volumes, nil
+}
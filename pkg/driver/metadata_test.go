@@ -0,0 +1,103 @@
+package driver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+func TestVolumeMetadataRoundTrip(t *testing.T) {
+	entry := &filer_pb.Entry{Name: "vol", IsDirectory: true}
+
+	meta, err := readVolumeMetadata(entry)
+	if err != nil || meta != nil {
+		t.Fatalf("expected no metadata on a fresh entry, got %v, %v", meta, err)
+	}
+
+	stored := newVolumeMetadata(1<<30, map[string]string{"replication": "001"}, "/.snapshots/src/snap")
+	stored.Populating = true
+	if err := stored.store(entry); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+
+	meta, err = readVolumeMetadata(entry)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !meta.matches(1<<30, map[string]string{"replication": "001"}, "/.snapshots/src/snap") || !meta.Populating {
+		t.Fatalf("metadata did not survive a round trip: %+v", meta)
+	}
+}
+
+func TestReadVolumeMetadataRejectsGarbage(t *testing.T) {
+	entry := &filer_pb.Entry{
+		Name:     "vol",
+		Extended: map[string][]byte{volumeMetadataKey: []byte("{")},
+	}
+	if _, err := readVolumeMetadata(entry); err == nil {
+		t.Fatal("expected an error for malformed metadata")
+	}
+}
+
+func TestVolumeMetadataMatches(t *testing.T) {
+	meta := &volumeMetadata{RequestedBytes: 100}
+
+	if !meta.matches(100, map[string]string{}, "") {
+		t.Error("nil and empty parameters should match")
+	}
+	if meta.matches(200, nil, "") {
+		t.Error("different capacity should not match")
+	}
+	if meta.matches(100, map[string]string{"collection": "c"}, "") {
+		t.Error("different parameters should not match")
+	}
+	if meta.matches(100, nil, "/buckets/other") {
+		t.Error("different content source should not match")
+	}
+}
+
+func TestContentSourcePath(t *testing.T) {
+	tests := []struct {
+		source *csi.VolumeContentSource
+		want   string
+	}{
+		{nil, ""},
+		{&csi.VolumeContentSource{}, ""},
+		{
+			&csi.VolumeContentSource{Type: &csi.VolumeContentSource_Snapshot{
+				Snapshot: &csi.VolumeContentSource_SnapshotSource{SnapshotId: "/.snapshots/vol/snap"},
+			}},
+			"/.snapshots/vol/snap",
+		},
+		{
+			&csi.VolumeContentSource{Type: &csi.VolumeContentSource_Volume{
+				Volume: &csi.VolumeContentSource_VolumeSource{VolumeId: "legacy"},
+			}},
+			"/buckets/legacy",
+		},
+	}
+	for _, tt := range tests {
+		if got, err := contentSourcePath(context.Background(), &SeaweedFsDriver{dirBuckets: "/buckets"}, tt.source); err != nil || got != tt.want {
+			t.Errorf("contentSourcePath(%v) = %q, %v, want %q", tt.source, got, err, tt.want)
+		}
+	}
+}
+
+func TestNewVolumeMetadataRecordsClaim(t *testing.T) {
+	meta := newVolumeMetadata(1024, map[string]string{
+		pvcNameParam:      "data",
+		pvcNamespaceParam: "apps",
+	}, "")
+
+	if meta.PVCName != "data" || meta.PVCNamespace != "apps" {
+		t.Fatalf("claim not recorded: %+v", meta)
+	}
+	if meta.RequestedBytes != 1024 || meta.CapacityBytes != 1024 {
+		t.Fatalf("capacity not recorded: %+v", meta)
+	}
+	if meta.CreationTime.IsZero() {
+		t.Fatal("creation time not recorded")
+	}
+}
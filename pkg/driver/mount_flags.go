@@ -0,0 +1,90 @@
+package driver
+
+import (
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+)
+
+// idMappingOwners are the mappings a plain `uid=N` or `gid=N` mount flag
+// sets, and the parameter naming the filer id it maps N to.
+var idMappingOwners = []struct {
+	flag, mapping, owner string
+}{
+	{"uid", "map.uid", "filerUid"},
+	{"gid", "map.gid", "filerGid"},
+}
+
+// mountFlagParameters are the volume attributes that can also be set per
+// volume through `key=value` mount flags, i.e. the PV's mountOptions.
+var mountFlagParameters = map[string]struct{}{
+	"cacheCapacityMB":    {},
+	"cacheMetaTtlSec":    {},
+	"concurrentReaders":  {},
+	"concurrentWriters":  {},
+	"chunkSizeLimitMB":   {},
+	"filerUid":           {},
+	"filerGid":           {},
+	"readRetryTime":      {},
+	"volumeServerAccess": {},
+}
+
+// applyMountFlags returns volContext overridden by the options in
+// mountFlags, and whether they ask for a read-only mount. Flags that have
+// no weed mount equivalent are rejected rather than silently dropped.
+func applyMountFlags(volContext map[string]string, mountFlags []string) (map[string]string, bool, error) {
+	overrides := make(map[string]string)
+	readOnly := false
+
+	for _, flag := range mountFlags {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(flag), "=")
+		switch {
+		case key == "ro" && !hasValue:
+			readOnly = true
+		case (key == "rw" || key == "defaults") && !hasValue:
+			// the defaults
+		case key == "allow_other" && !hasValue:
+			overrides["allowOthers"] = "true"
+		case key == "nonempty" && !hasValue:
+			overrides["nonempty"] = "true"
+		case key == "uid" && hasValue:
+			overrides["map.uid"] = value
+		case key == "gid" && hasValue:
+			overrides["map.gid"] = value
+		default:
+			if _, ok := mountFlagParameters[key]; !ok || !hasValue {
+				return nil, false, fmt.Errorf("unsupported mount flag %q", flag)
+			}
+			overrides[key] = value
+		}
+	}
+
+	// a plain local id maps to the filer owner configured for the volume,
+	// never implicitly to root
+	for _, id := range idMappingOwners {
+		value, ok := overrides[id.mapping]
+		if !ok {
+			continue
+		}
+		if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+			continue
+		}
+		owner, ok := overrides[id.owner]
+		if !ok {
+			owner = volContext[id.owner]
+		}
+		if owner == "" {
+			return nil, false, fmt.Errorf("mount flag %s=%s needs the filer owner in %s, or a <local>:<filer> mapping", id.flag, value, id.owner)
+		}
+		overrides[id.mapping] = value + ":" + owner
+	}
+
+	// the flags must satisfy the same rules as the attributes they replace
+	if err := validateVolumeParameters(overrides); err != nil {
+		return nil, false, fmt.Errorf("invalid mount flags: %v", err)
+	}
+	effective := cloneVolumeContext(volContext)
+	maps.Copy(effective, overrides)
+	return effective, readOnly, nil
+}
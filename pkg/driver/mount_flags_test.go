@@ -0,0 +1,88 @@
+package driver
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyMountFlags(t *testing.T) {
+	volContext := map[string]string{"concurrentWriters": "32", "collection": "c1"}
+
+	effective, readOnly, err := applyMountFlags(volContext, []string{
+		"ro", "allow_other", "nonempty", "uid=1000:0", "gid=1000:0", "concurrentWriters=64",
+	})
+	if err != nil {
+		t.Fatalf("applyMountFlags: %v", err)
+	}
+	if !readOnly {
+		t.Fatalf("ro flag did not make the mount read-only")
+	}
+	want := map[string]string{
+		"collection":        "c1",
+		"concurrentWriters": "64",
+		"allowOthers":       "true",
+		"nonempty":          "true",
+		"map.uid":           "1000:0",
+		"map.gid":           "1000:0",
+	}
+	for k, v := range want {
+		if effective[k] != v {
+			t.Fatalf("effective[%q] = %q, want %q", k, effective[k], v)
+		}
+	}
+	if volContext["concurrentWriters"] != "32" {
+		t.Fatalf("applyMountFlags modified the volume context")
+	}
+
+	mounter := &mountServiceMounter{driver: &SeaweedFsDriver{}, volumeID: "/buckets/pvc-1234", volContext: effective}
+	args, err := mounter.buildMountArgs("/staging", "/cache", "/socket", []string{"filer:8888"})
+	if err != nil {
+		t.Fatalf("buildMountArgs: %v", err)
+	}
+	for _, arg := range []string{"-allowOthers=true", "-nonempty=true", "-concurrentWriters=64", "-map.uid=1000:0"} {
+		if !slices.Contains(args, arg) {
+			t.Fatalf("mount args do not contain %s: %v", arg, args)
+		}
+	}
+}
+
+func TestApplyMountFlagsMapsPlainIdsToFilerOwner(t *testing.T) {
+	effective, _, err := applyMountFlags(map[string]string{"filerUid": "500", "filerGid": "600"}, []string{"uid=1000", "gid=2000"})
+	if err != nil {
+		t.Fatalf("applyMountFlags: %v", err)
+	}
+	if effective["map.uid"] != "1000:500" || effective["map.gid"] != "2000:600" {
+		t.Fatalf("map.uid = %q, map.gid = %q, want 1000:500 and 2000:600", effective["map.uid"], effective["map.gid"])
+	}
+
+	// the owner may come along with the mount flags
+	effective, _, err = applyMountFlags(map[string]string{"filerUid": "500"}, []string{"uid=1000", "filerUid=700"})
+	if err != nil || effective["map.uid"] != "1000:700" {
+		t.Fatalf("map.uid = %q, %v, want 1000:700", effective["map.uid"], err)
+	}
+
+	if _, _, err := applyMountFlags(nil, []string{"uid=1000"}); err == nil || !strings.Contains(err.Error(), "filerUid") {
+		t.Fatalf("applyMountFlags(uid=1000) without filerUid = %v, want an error naming filerUid", err)
+	}
+	if _, _, err := applyMountFlags(map[string]string{"filerUid": "500"}, []string{"gid=2000"}); err == nil || !strings.Contains(err.Error(), "filerGid") {
+		t.Fatalf("applyMountFlags(gid=2000) without filerGid = %v, want an error naming filerGid", err)
+	}
+	if _, _, err := applyMountFlags(map[string]string{"filerUid": "500"}, []string{"uid=alice"}); err == nil {
+		t.Fatalf("applyMountFlags(uid=alice) succeeded, want error")
+	}
+}
+
+func TestApplyMountFlagsRejectsUnsupported(t *testing.T) {
+	for _, flags := range [][]string{
+		{"noexec"},
+		{"ro=true"},
+		{"cacheCapacityMB"},
+		{"collection=other"},
+		{"concurrentWriters=many"},
+	} {
+		if _, _, err := applyMountFlags(nil, flags); err == nil {
+			t.Fatalf("applyMountFlags(%v) succeeded, want error", flags)
+		}
+	}
+}
@@ -1,14 +1,23 @@
 package driver
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/datalocality"
 	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
-	"github.com/seaweedfs/seaweedfs/weed/glog"
+)
+
+// StorageClass parameters limiting the resources of the weed mount process
+// of a volume, applied by the mount service through a cgroup. 0 or unset
+// means unlimited.
+const (
+	memoryLimitParam = "memoryLimitMB"
+	cpuLimitParam    = "cpuLimitMillicores"
 )
 
 type Unmounter interface {
@@ -24,7 +33,9 @@ type mountServiceMounter struct {
 	volumeID   string
 	readOnly   bool
 	volContext map[string]string
-	client     *mountmanager.Client
+	// credentials replace the node's security.toml for the mount
+	credentials *mountmanager.MountCredentials
+	client      *mountmanager.Client
 }
 
 type mountServiceUnmounter struct {
@@ -32,8 +43,8 @@ type mountServiceUnmounter struct {
 	volumeID string
 }
 
-func newMounter(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string) (Mounter, error) {
-	client, err := mountmanager.NewClient(driver.mountEndpoint)
+func newMounter(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string, credentials *mountmanager.MountCredentials) (Mounter, error) {
+	client, err := driver.mountServiceClient()
 	if err != nil {
 		return nil, err
 	}
@@ -44,11 +55,12 @@ func newMounter(volumeID string, readOnly bool, driver *SeaweedFsDriver, volCont
 	}
 
 	return &mountServiceMounter{
-		driver:     driver,
-		volumeID:   volumeID,
-		readOnly:   readOnly,
-		volContext: contextCopy,
-		client:     client,
+		driver:      driver,
+		volumeID:    volumeID,
+		readOnly:    readOnly,
+		volContext:  contextCopy,
+		credentials: credentials,
+		client:      client,
 	}, nil
 }
 
@@ -62,7 +74,11 @@ func (m *mountServiceMounter) Mount(target string) (Unmounter, error) {
 		filers[i] = string(address)
 	}
 
-	cacheDir := GetCacheDir(m.driver.CacheDir, m.volumeID)
+	cacheBase, err := m.driver.volumeCacheBase(m.volContext)
+	if err != nil {
+		return nil, err
+	}
+	cacheDir := GetCacheDir(cacheBase, m.volumeID)
 	localSocket := GetLocalSocket(m.driver.volumeSocketDir, m.volumeID)
 
 	args, err := m.buildMountArgs(target, cacheDir, localSocket, filers)
@@ -71,11 +87,14 @@ func (m *mountServiceMounter) Mount(target string) (Unmounter, error) {
 	}
 
 	req := &mountmanager.MountRequest{
-		VolumeID:    m.volumeID,
-		TargetPath:  target,
-		CacheDir:    cacheDir,
-		MountArgs:   args,
-		LocalSocket: localSocket,
+		VolumeID:           m.volumeID,
+		TargetPath:         target,
+		CacheDir:           cacheDir,
+		MountArgs:          args,
+		LocalSocket:        localSocket,
+		Credentials:        m.credentials,
+		MemoryLimitMB:      resourceLimit(m.volContext, memoryLimitParam),
+		CPULimitMillicores: resourceLimit(m.volContext, cpuLimitParam),
 	}
 
 	_, err = m.client.Mount(req)
@@ -100,16 +119,27 @@ func (m *mountServiceMounter) buildMountArgs(targetPath, cacheDir, localSocket s
 		volumeContext = map[string]string{}
 	}
 
+	cluster, volumePath, err := parseVolumeId(m.volumeID)
+	if err != nil {
+		return nil, err
+	}
+
 	var filerPath string
-	if path.IsAbs(m.volumeID) {
+	if path.IsAbs(volumePath) {
 		// path already resolved in controller and passed as volumeID
-		filerPath = m.volumeID
+		filerPath = volumePath
 	} else {
 		// non-absolute-path volume ID, volume is either legacy or this is a static provision
 		contextPath := volumeContext["path"]
 		if contextPath == "" {
 			// Backward-compatibility for legacy volume ID
-			filerPath = path.Join("/buckets", m.volumeID)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			bucketsDir, err := m.driver.bucketsDir(ctx)
+			if err != nil {
+				return nil, err
+			}
+			filerPath = path.Join(bucketsDir, volumePath)
 		} else {
 			// This is a static provision
 			// Always use the context path parameter as filerPath
@@ -138,23 +168,21 @@ func (m *mountServiceMounter) buildMountArgs(targetPath, cacheDir, localSocket s
 	}
 
 	argsMap := map[string]string{
-		"collection":         collection,
-		"collectionQuotaMB":  initialCollectionQuotaMB(volumeContext[volumeCapacityKey]),
-		"filer":              strings.Join(filers, ","),
-		"filer.path":         filerPath,
-		"cacheCapacityMB":    strconv.Itoa(m.driver.CacheCapacityMB),
-		"cacheMetaTtlSec":    strconv.Itoa(m.driver.CacheMetaTtlSec),
-		"concurrentReaders":  strconv.Itoa(m.driver.ConcurrentReaders),
-		"concurrentWriters":  strconv.Itoa(m.driver.ConcurrentWriters),
-		"map.uid":            m.driver.UidMap,
-		"map.gid":            m.driver.GidMap,
-		"disk":               "",
-		"dataCenter":         "",
-		"replication":        "",
-		"ttl":                "",
-		"chunkSizeLimitMB":   "",
-		"volumeServerAccess": "",
-		"readRetryTime":      "",
+		"collection":        collection,
+		"collectionQuotaMB": initialCollectionQuotaMB(volumeContext[volumeCapacityKey]),
+		"filer":             strings.Join(filers, ","),
+		"filer.path":        filerPath,
+		"cacheCapacityMB":   strconv.Itoa(m.driver.CacheCapacityMB),
+		"cacheMetaTtlSec":   strconv.Itoa(m.driver.CacheMetaTtlSec),
+		"concurrentReaders": strconv.Itoa(m.driver.ConcurrentReaders),
+		"concurrentWriters": strconv.Itoa(m.driver.ConcurrentWriters),
+		"map.uid":           m.driver.UidMap,
+		"map.gid":           m.driver.GidMap,
+	}
+
+	if cluster.filers != "" {
+		// volume of another cluster than the node's
+		argsMap["filer"] = cluster.filers
 	}
 
 	dataLocality := m.driver.DataLocality
@@ -172,40 +200,25 @@ func (m *mountServiceMounter) buildMountArgs(targetPath, cacheDir, localSocket s
 	}
 
 	switch dataLocality {
-	case datalocality.Write_preferLocalDc:
+	case datalocality.Write_preferLocalDc, datalocality.Read_preferLocalDc:
+		// weed mount orders the replicas it reads from and places its
+		// writes by the same data center, so preferring local reads on a
+		// writable mount also prefers local writes; neither is strict
 		argsMap["dataCenter"] = dataCenter
 	}
 
-	parameterArgMap := map[string]string{
-		"uidMap":   "map.uid",
-		"gidMap":   "map.gid",
-		"diskType": "disk",
+	// Unknown keys have been logged when the volume was staged
+	for key, value := range volumeContext {
+		if param := volumeParameters[key]; param.mountArg != "" && value != "" {
+			argsMap[param.mountArg] = value
+		}
 	}
 
-	ignoredArgs := map[string]struct{}{
-		"collectionQuotaMB": {},
-		"dataLocality":      {},
-		"path":              {},
-		"parentDir":         {},
-		"volumeName":        {},
-		volumeCapacityKey:   {},
+	if sharesCollection(volumeContext) {
+		argsMap["collectionQuotaMB"] = ""
 	}
 
-	for key, value := range volumeContext {
-		if _, ignored := ignoredArgs[key]; ignored {
-			continue
-		}
-		if mapped, ok := parameterArgMap[key]; ok {
-			key = mapped
-		}
-		if _, ok := argsMap[key]; !ok {
-			glog.Warningf("VolumeContext '%s' ignored", key)
-			continue
-		}
-		if value != "" {
-			argsMap[key] = value
-		}
-	}
+	m.driver.applyNodeLimits(m.volumeID, argsMap)
 
 	for key, value := range argsMap {
 		if value == "" {
@@ -217,6 +230,16 @@ func (m *mountServiceMounter) buildMountArgs(targetPath, cacheDir, localSocket s
 	return args, nil
 }
 
+// resourceLimit returns the resource limit key of volumeContext, 0 if unset.
+// Its value has been validated by CreateVolume and NodeStageVolume.
+func resourceLimit(volumeContext map[string]string, key string) int64 {
+	limit, err := strconv.ParseInt(volumeContext[key], 10, 64)
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
 func initialCollectionQuotaMB(capacityBytes string) string {
 	capacity, err := strconv.ParseInt(capacityBytes, 10, 64)
 	if err != nil || capacity <= 1 {
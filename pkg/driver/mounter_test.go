@@ -2,6 +2,7 @@ package driver
 
 import (
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -28,6 +29,60 @@ func TestBuildMountArgsIncludesInitialCollectionQuota(t *testing.T) {
 	}
 }
 
+func TestBuildMountArgsSharedDirHasNoCollectionQuota(t *testing.T) {
+	mounter := &mountServiceMounter{
+		driver:   &SeaweedFsDriver{},
+		volumeID: "/buckets/shared/pvc-1234",
+		volContext: map[string]string{
+			volumeCapacityKey:   "5368709120",
+			sharedDirParam:      "/buckets/shared",
+			"collectionQuotaMB": "100",
+		},
+	}
+
+	args, err := mounter.buildMountArgs("/staging", "/cache", "/socket", []string{"filer:8888"})
+	if err != nil {
+		t.Fatalf("buildMountArgs: %v", err)
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-collectionQuotaMB=") {
+			t.Fatalf("shared collection mounted with %s: %v", arg, args)
+		}
+	}
+}
+
+func TestBuildMountArgsForwardsParametersAsSchemaSays(t *testing.T) {
+	mounter := &mountServiceMounter{
+		driver:   &SeaweedFsDriver{},
+		volumeID: "/buckets/pvc-1234",
+		volContext: map[string]string{
+			"diskType":        "ssd",
+			"uidMap":          "1000:0",
+			"ttl":             "3d",
+			"legacyOption":    "x",
+			deletePolicyParam: deletePolicyTrash,
+			memoryLimitParam:  "512",
+		},
+	}
+
+	args, err := mounter.buildMountArgs("/staging", "/cache", "/socket", []string{"filer:8888"})
+	if err != nil {
+		t.Fatalf("buildMountArgs: %v", err)
+	}
+	for _, want := range []string{"-disk=ssd", "-map.uid=1000:0", "-ttl=3d"} {
+		if !slices.Contains(args, want) {
+			t.Errorf("mount args do not contain %s: %v", want, args)
+		}
+	}
+	for _, arg := range args {
+		for _, consumed := range []string{"legacyOption", deletePolicyParam, memoryLimitParam} {
+			if strings.HasPrefix(arg, "-"+consumed+"=") {
+				t.Errorf("%s passed to weed mount: %v", consumed, args)
+			}
+		}
+	}
+}
+
 func TestInitialCollectionQuotaMBRoundsUp(t *testing.T) {
 	if got, want := initialCollectionQuotaMB("1048577"), "2"; got != want {
 		t.Fatalf("initialCollectionQuotaMB = %q, want %q", got, want)
@@ -39,3 +94,58 @@ func TestInitialCollectionQuotaMBDisablesOneByteSentinel(t *testing.T) {
 		t.Fatalf("initialCollectionQuotaMB = %q, want empty", got)
 	}
 }
+
+func TestBuildMountArgsDataLocality(t *testing.T) {
+	tests := []struct {
+		name     string
+		locality string
+		readOnly bool
+		want     []string
+		wantNot  []string
+	}{
+		{"prefer local dc", "write_preferLocalDc", false, []string{"-dataCenter=dc1"}, []string{"-rack=r1"}},
+		{"read local dc, writer", "read_preferLocalDc", false, []string{"-dataCenter=dc1"}, nil},
+		{"read local dc, reader", "read_preferLocalDc", true, []string{"-dataCenter=dc1"}, nil},
+	}
+	for _, tt := range tests {
+		mounter := &mountServiceMounter{
+			driver:     &SeaweedFsDriver{DataCenter: "dc1", Rack: "r1"},
+			volumeID:   "/buckets/pvc-1234",
+			readOnly:   tt.readOnly,
+			volContext: map[string]string{"dataLocality": tt.locality},
+		}
+		args, err := mounter.buildMountArgs("/staging", "/cache", "/socket", []string{"filer:8888"})
+		if err != nil {
+			t.Fatalf("%s: buildMountArgs: %v", tt.name, err)
+		}
+		for _, arg := range tt.want {
+			if !slices.Contains(args, arg) {
+				t.Errorf("%s: mount args %v do not contain %s", tt.name, args, arg)
+			}
+		}
+		for _, arg := range tt.wantNot {
+			if slices.Contains(args, arg) {
+				t.Errorf("%s: mount args %v unexpectedly contain %s", tt.name, args, arg)
+			}
+		}
+	}
+}
+
+func TestCheckVolumeDataLocality(t *testing.T) {
+	tests := []struct {
+		params  map[string]string
+		wantErr bool
+	}{
+		{map[string]string{}, false},
+		{map[string]string{"dataLocality": "write_preferLocalDc", "replication": "100"}, false},
+		{map[string]string{"dataLocality": "read_preferLocalDc"}, false},
+		{map[string]string{"dataLocality": "write_onlyLocalDc", "replication": "011"}, true},
+		{map[string]string{"dataLocality": "write_preferLocalRack"}, true},
+		{map[string]string{"dataLocality": "write_everywhere"}, true},
+	}
+	for _, tt := range tests {
+		if err := checkVolumeDataLocality(tt.params); (err != nil) != tt.wantErr {
+			t.Errorf("checkVolumeDataLocality(%v) = %v, want error %v", tt.params, err, tt.wantErr)
+		}
+	}
+}
@@ -18,16 +18,26 @@ import (
 
 // MounterFactory creates a Mounter for a volume. It is a field on NodeServer
 // so tests can substitute a fake that does not touch the real mount service.
-type MounterFactory func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string) (Mounter, error)
+type MounterFactory func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string, credentials *mountmanager.MountCredentials) (Mounter, error)
 
 // CapacityFn resolves the desired capacity (bytes) for a volume from the
-// orchestrator. Tests can replace this to avoid real Kubernetes API calls.
-type CapacityFn func(volumeID string) (int64, error)
+// orchestrator, reading the filer with credentials if they are given. Tests
+// can replace this to avoid real Kubernetes API calls.
+type CapacityFn func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error)
 
 // BindMountFn performs a bind mount of source onto target, optionally
 // read-only. Used by Volume.Publish and overridden in tests.
 type BindMountFn func(source, target string, readOnly bool) error
 
+// VolumeStatsFn reads the usage of the filesystem mounted at a path.
+// Overridden in tests so they do not depend on a real mount.
+type VolumeStatsFn func(path string) (*volumeUsage, error)
+
+type volumeUsage struct {
+	totalBytes, availableBytes, usedBytes int64
+	totalInodes, freeInodes, usedInodes   int64
+}
+
 // HealthCheckFn reports whether a staging path has a live, responsive FUSE
 // mount. Overridden in tests to simulate a crashed mount.
 type HealthCheckFn func(stagingPath string) bool
@@ -63,6 +73,7 @@ type NodeServer struct {
 	cleanupStagingFn func(stagingPath string) error
 	unmountFn        func(path string) error
 	bindMountFn      BindMountFn
+	volumeStatsFn    VolumeStatsFn
 }
 
 var _ = csi.NodeServer(&NodeServer{})
@@ -87,6 +98,20 @@ func (ns *NodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 	if stagingTargetPath == "" {
 		return nil, status.Error(codes.InvalidArgument, "Target path missing in request")
 	}
+	if err := checkVolumeContext(volumeID, req.GetVolumeContext()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid volume context: %v", err)
+	}
+	volContext, flagsReadOnly, err := applyMountFlags(req.GetVolumeContext(), req.GetVolumeCapability().GetMount().GetMountFlags())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	credentials, err := credentialsFromSecrets(req.GetSecrets())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := ns.Driver.volumeCacheBase(volContext); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
 	volumeMutex := ns.getVolumeMutex(volumeID)
 	volumeMutex.Lock()
@@ -107,8 +132,9 @@ func (ns *NodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		// This preserves the existing FUSE mount and avoids disrupting any published volumes
 		glog.Infof("volume %s has existing healthy mount at %s, rebuilding cache", volumeID, stagingTargetPath)
 		volume := ns.rebuildVolumeFromStaging(volumeID, stagingTargetPath)
-		volume.volContext = req.GetVolumeContext()
-		volume.readOnly = isVolumeReadOnly(req)
+		volume.volContext = volContext
+		volume.readOnly = isVolumeReadOnly(req) || flagsReadOnly
+		volume.credentials = credentials
 		ns.volumes.Store(volumeID, volume)
 		glog.Infof("volume %s cache rebuilt from existing staging at %s", volumeID, stagingTargetPath)
 		return &csi.NodeStageVolumeResponse{}, nil
@@ -123,10 +149,9 @@ func (ns *NodeServer) NodeStageVolume(ctx context.Context, req *csi.NodeStageVol
 		}
 	}
 
-	volContext := req.GetVolumeContext()
-	readOnly := isVolumeReadOnly(req)
+	readOnly := isVolumeReadOnly(req) || flagsReadOnly
 
-	volume, err := ns.stageNewVolume(volumeID, stagingTargetPath, volContext, readOnly)
+	volume, err := ns.stageNewVolume(volumeID, stagingTargetPath, volContext, readOnly, credentials)
 	if err != nil {
 		// node stage is unsuccessful
 		ns.removeVolumeMutex(volumeID)
@@ -201,10 +226,21 @@ func (ns *NodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 			}
 
 			// Re-stage the volume using the shared helper
-			volContext := req.GetVolumeContext()
-			readOnly := isPublishVolumeReadOnly(req)
+			volContext, flagsReadOnly, err := applyMountFlags(req.GetVolumeContext(), req.GetVolumeCapability().GetMount().GetMountFlags())
+			if err != nil {
+				ns.removeVolumeMutex(volumeID)
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			readOnly := isPublishVolumeReadOnly(req) || flagsReadOnly
+			// the node-stage secrets are not part of NodePublishVolume,
+			// the node-publish secrets stand in for them
+			credentials, err := credentialsFromSecrets(req.GetSecrets())
+			if err != nil {
+				ns.removeVolumeMutex(volumeID)
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
 
-			newVolume, err := ns.stageNewVolume(volumeID, stagingTargetPath, volContext, readOnly)
+			newVolume, err := ns.stageNewVolume(volumeID, stagingTargetPath, volContext, readOnly, credentials)
 			if err != nil {
 				ns.removeVolumeMutex(volumeID)
 				return nil, status.Errorf(codes.Internal, "failed to re-stage volume: %v", err)
@@ -232,12 +268,14 @@ func (ns *NodeServer) NodePublishVolume(ctx context.Context, req *csi.NodePublis
 // This is used for self-healing when the CSI driver restarts but the FUSE mount is still active.
 // Note: The returned Volume won't have an unmounter, so Unstage will need special handling.
 func (ns *NodeServer) rebuildVolumeFromStaging(volumeID string, stagingPath string) *Volume {
+	stagedDevice, _ := getMountDevice(stagingPath)
 	return &Volume{
-		VolumeId:    volumeID,
-		StagedPath:  stagingPath,
-		driver:      ns.Driver,
-		localSocket: mountmanager.LocalSocketPath(ns.Driver.volumeSocketDir, volumeID),
-		bindMountFn: ns.bindMountFn,
+		VolumeId:     volumeID,
+		StagedPath:   stagingPath,
+		driver:       ns.Driver,
+		localSocket:  mountmanager.LocalSocketPath(ns.Driver.volumeSocketDir, volumeID),
+		bindMountFn:  ns.bindMountFn,
+		stagedDevice: stagedDevice,
 		// mounter and unmounter are nil - this is intentional
 		// The FUSE process is already running, we just need to track the volume
 		// The mount service will have the mount tracked if it's still alive
@@ -298,7 +336,8 @@ func (ns *NodeServer) NodeGetInfo(ctx context.Context, req *csi.NodeGetInfoReque
 	glog.V(3).Infof("node get info, node id: %s", ns.Driver.nodeID)
 
 	return &csi.NodeGetInfoResponse{
-		NodeId: ns.Driver.nodeID,
+		NodeId:             ns.Driver.nodeID,
+		AccessibleTopology: nodeTopology(ns.Driver.DataCenter, ns.Driver.Rack),
 	}, nil
 }
 
@@ -321,6 +360,20 @@ func (ns *NodeServer) NodeGetCapabilities(ctx context.Context, req *csi.NodeGetC
 					},
 				},
 			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_GET_VOLUME_STATS,
+					},
+				},
+			},
+			{
+				Type: &csi.NodeServiceCapability_Rpc{
+					Rpc: &csi.NodeServiceCapability_RPC{
+						Type: csi.NodeServiceCapability_RPC_VOLUME_CONDITION,
+					},
+				},
+			},
 		},
 	}, nil
 }
@@ -399,6 +452,74 @@ func (ns *NodeServer) NodeExpandVolume(ctx context.Context, req *csi.NodeExpandV
 	return &csi.NodeExpandVolumeResponse{}, nil
 }
 
+func (ns *NodeServer) NodeGetVolumeStats(ctx context.Context, req *csi.NodeGetVolumeStatsRequest) (*csi.NodeGetVolumeStatsResponse, error) {
+	volumeID := req.GetVolumeId()
+	volumePath := req.GetVolumePath()
+
+	glog.V(4).Infof("node get volume stats %s at %s", volumeID, volumePath)
+
+	if len(volumeID) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume ID missing in request")
+	}
+
+	if len(volumePath) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Volume path missing in request")
+	}
+
+	volume, ok := ns.volumes.Load(volumeID)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "volume %s is not staged on this node", volumeID)
+	}
+	if _, err := os.Lstat(volumePath); os.IsNotExist(err) {
+		return nil, status.Errorf(codes.NotFound, "volume path %s does not exist", volumePath)
+	}
+
+	// Judge health on the FUSE mount itself rather than on a bind mount of it
+	healthPath := volume.(*Volume).StagedPath
+	if healthPath == "" {
+		healthPath = volumePath
+	}
+	if !ns.checkHealth(healthPath) {
+		// statfs on a dead FUSE mount would fail or hang, report only the condition
+		return &csi.NodeGetVolumeStatsResponse{
+			VolumeCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  fmt.Sprintf("FUSE mount at %s is not healthy", healthPath),
+			},
+		}, nil
+	}
+
+	usage, err := ns.volumeStatsFn(volumePath)
+	if err != nil {
+		return &csi.NodeGetVolumeStatsResponse{
+			VolumeCondition: &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  fmt.Sprintf("cannot read usage of %s: %v", volumePath, err),
+			},
+		}, nil
+	}
+
+	return &csi.NodeGetVolumeStatsResponse{
+		Usage: []*csi.VolumeUsage{
+			{
+				Unit:      csi.VolumeUsage_BYTES,
+				Total:     usage.totalBytes,
+				Available: usage.availableBytes,
+				Used:      usage.usedBytes,
+			},
+			{
+				Unit:      csi.VolumeUsage_INODES,
+				Total:     usage.totalInodes,
+				Available: usage.freeInodes,
+				Used:      usage.usedInodes,
+			},
+		},
+		VolumeCondition: &csi.VolumeCondition{
+			Message: "FUSE mount is healthy",
+		},
+	}, nil
+}
+
 func (ns *NodeServer) NodeCleanup() {
 	ns.stopOnce.Do(func() {
 		close(ns.stopCh)
@@ -422,9 +543,9 @@ func (ns *NodeServer) removeVolumeMutex(volumeID string) {
 // This is a helper method used by both NodeStageVolume and NodePublishVolume (for re-staging).
 // Both the mounter and capacity lookup are resolved via NodeServer fields so
 // tests can inject fakes that do not touch the real mount service or k8s API.
-func (ns *NodeServer) stageNewVolume(volumeID, stagingTargetPath string, volContext map[string]string, readOnly bool) (*Volume, error) {
+func (ns *NodeServer) stageNewVolume(volumeID, stagingTargetPath string, volContext map[string]string, readOnly bool, credentials *mountmanager.MountCredentials) (*Volume, error) {
 	effectiveVolContext := cloneVolumeContext(volContext)
-	capacity, hasCapacity, err := ns.resolveVolumeCapacity(volumeID, effectiveVolContext)
+	capacity, hasCapacity, err := ns.resolveVolumeCapacity(volumeID, effectiveVolContext, credentials)
 	if err != nil {
 		return nil, err
 	}
@@ -432,7 +553,7 @@ func (ns *NodeServer) stageNewVolume(volumeID, stagingTargetPath string, volCont
 		effectiveVolContext[volumeCapacityKey] = strconv.FormatInt(capacity, 10)
 	}
 
-	mounter, err := ns.mounterFactory(volumeID, readOnly, ns.Driver, effectiveVolContext)
+	mounter, err := ns.mounterFactory(volumeID, readOnly, ns.Driver, effectiveVolContext, credentials)
 	if err != nil {
 		return nil, err
 	}
@@ -441,9 +562,11 @@ func (ns *NodeServer) stageNewVolume(volumeID, stagingTargetPath string, volCont
 	volume.bindMountFn = ns.bindMountFn
 	volume.volContext = effectiveVolContext
 	volume.readOnly = readOnly
+	volume.credentials = credentials
 	if err := volume.Stage(stagingTargetPath); err != nil {
 		return nil, err
 	}
+	volume.stagedDevice, _ = getMountDevice(stagingTargetPath)
 
 	// Apply quota if available.
 	if hasCapacity {
@@ -470,9 +593,9 @@ func cloneVolumeContext(volContext map[string]string) map[string]string {
 	return cloned
 }
 
-func (ns *NodeServer) resolveVolumeCapacity(volumeID string, volContext map[string]string) (int64, bool, error) {
+func (ns *NodeServer) resolveVolumeCapacity(volumeID string, volContext map[string]string, credentials *mountmanager.MountCredentials) (int64, bool, error) {
 	if ns.capacityFn != nil {
-		capacity, err := ns.capacityFn(volumeID)
+		capacity, err := ns.capacityFn(volumeID, credentials)
 		if err == nil {
 			return capacity, true, nil
 		}
@@ -4,9 +4,15 @@
 package driver
 
 import (
+	"context"
 	"errors"
 	"path/filepath"
 	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // fakeMounter records Mount/Unmount calls and reports success without
@@ -45,10 +51,10 @@ func newTestNodeServer(t *testing.T, fake *fakeMounter) *NodeServer {
 		Driver:        &SeaweedFsDriver{},
 		volumeMutexes: NewKeyMutex(),
 		stopCh:        make(chan struct{}),
-		mounterFactory: func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string) (Mounter, error) {
+		mounterFactory: func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string, credentials *mountmanager.MountCredentials) (Mounter, error) {
 			return fake, nil
 		},
-		capacityFn: func(volumeID string) (int64, error) {
+		capacityFn: func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
 			// Skip quota application in tests.
 			return 0, errors.New("capacity not available in test")
 		},
@@ -59,6 +65,9 @@ func newTestNodeServer(t *testing.T, fake *fakeMounter) *NodeServer {
 			// Create the target so follow-up checkMount calls see a directory.
 			return nil
 		},
+		volumeStatsFn: func(path string) (*volumeUsage, error) {
+			return &volumeUsage{totalBytes: 100, availableBytes: 60, usedBytes: 40, totalInodes: 10, freeInodes: 7, usedInodes: 3}, nil
+		},
 	}
 	return ns
 }
@@ -71,7 +80,7 @@ func TestStageNewVolumeUsesInjectedFactories(t *testing.T) {
 	// it does not exist, so point it at a tempdir.
 	stagingPath := filepath.Join(t.TempDir(), "staging")
 
-	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{"collection": "c"}, false)
+	vol, err := ns.stageNewVolume("vol-1", stagingPath, map[string]string{"collection": "c"}, false, nil)
 	if err != nil {
 		t.Fatalf("stageNewVolume failed: %v", err)
 	}
@@ -92,15 +101,92 @@ func TestStageNewVolumeUsesInjectedFactories(t *testing.T) {
 	}
 }
 
+func TestStageNewVolumeReadsCapacityWithStageCredentials(t *testing.T) {
+	ns := newTestNodeServer(t, &fakeMounter{})
+	var got *mountmanager.MountCredentials
+	ns.capacityFn = func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
+		got = credentials
+		return 0, errors.New("capacity not available in test")
+	}
+
+	credentials := &mountmanager.MountCredentials{FilerSigningKey: "tenant"}
+	if _, err := ns.stageNewVolume("vol-1", filepath.Join(t.TempDir(), "staging"), nil, false, credentials); err != nil {
+		t.Fatalf("stageNewVolume failed: %v", err)
+	}
+	if got != credentials {
+		t.Fatalf("capacity was read with credentials %v, want those of the stage request", got)
+	}
+}
+
 func TestStageNewVolumePropagatesMounterError(t *testing.T) {
 	wantErr := errors.New("mount refused")
 	ns := newTestNodeServer(t, nil)
-	ns.mounterFactory = func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string) (Mounter, error) {
+	ns.mounterFactory = func(volumeID string, readOnly bool, driver *SeaweedFsDriver, volContext map[string]string, credentials *mountmanager.MountCredentials) (Mounter, error) {
 		return nil, wantErr
 	}
 
-	_, err := ns.stageNewVolume("vol-1", t.TempDir(), nil, false)
+	_, err := ns.stageNewVolume("vol-1", t.TempDir(), nil, false, nil)
 	if !errors.Is(err, wantErr) {
 		t.Fatalf("expected error %v, got %v", wantErr, err)
 	}
 }
+
+func TestNodeGetVolumeStatsReportsUsage(t *testing.T) {
+	ns := newTestNodeServer(t, &fakeMounter{})
+	stagingPath := t.TempDir()
+	ns.volumes.Store("vol-1", &Volume{VolumeId: "vol-1", StagedPath: stagingPath})
+
+	resp, err := ns.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{
+		VolumeId:   "vol-1",
+		VolumePath: stagingPath,
+	})
+	if err != nil {
+		t.Fatalf("NodeGetVolumeStats: %v", err)
+	}
+	if resp.VolumeCondition.GetAbnormal() {
+		t.Fatalf("healthy volume reported abnormal: %v", resp.VolumeCondition)
+	}
+	if len(resp.Usage) != 2 {
+		t.Fatalf("expected bytes and inodes usage, got %v", resp.Usage)
+	}
+	if bytes := resp.Usage[0]; bytes.Unit != csi.VolumeUsage_BYTES || bytes.Total != 100 || bytes.Used != 40 || bytes.Available != 60 {
+		t.Errorf("unexpected bytes usage: %v", bytes)
+	}
+	if inodes := resp.Usage[1]; inodes.Unit != csi.VolumeUsage_INODES || inodes.Total != 10 || inodes.Used != 3 || inodes.Available != 7 {
+		t.Errorf("unexpected inodes usage: %v", inodes)
+	}
+}
+
+func TestNodeGetVolumeStatsReportsBrokenMount(t *testing.T) {
+	ns := newTestNodeServer(t, &fakeMounter{})
+	ns.isHealthyFn = func(path string) bool { return false }
+	ns.volumeStatsFn = func(path string) (*volumeUsage, error) {
+		t.Fatal("usage must not be read from a broken mount")
+		return nil, nil
+	}
+	stagingPath := t.TempDir()
+	ns.volumes.Store("vol-1", &Volume{VolumeId: "vol-1", StagedPath: stagingPath})
+
+	resp, err := ns.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{
+		VolumeId:   "vol-1",
+		VolumePath: stagingPath,
+	})
+	if err != nil {
+		t.Fatalf("NodeGetVolumeStats: %v", err)
+	}
+	if !resp.VolumeCondition.GetAbnormal() {
+		t.Fatalf("broken mount reported as normal: %v", resp.VolumeCondition)
+	}
+}
+
+func TestNodeGetVolumeStatsUnknownVolume(t *testing.T) {
+	ns := newTestNodeServer(t, &fakeMounter{})
+
+	_, err := ns.NodeGetVolumeStats(context.Background(), &csi.NodeGetVolumeStatsRequest{
+		VolumeId:   "missing",
+		VolumePath: t.TempDir(),
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package driver
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/datalocality"
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+)
+
+// parameterValidator checks the value of a StorageClass parameter or volume
+// attribute and describes what is expected when it does not fit.
+type parameterValidator func(value string) error
+
+// volumeParameter describes a StorageClass parameter or volume attribute.
+type volumeParameter struct {
+	// validate checks the value, nil accepts any value
+	validate parameterValidator
+	// mountArg is the weed mount option the value is passed to, empty for
+	// parameters the driver consumes itself
+	mountArg string
+}
+
+// volumeParameters is the schema of the StorageClass parameters and volume
+// attributes understood by the driver. CreateVolume rejects volumes using
+// anything else, so that a typo fails provisioning instead of being ignored
+// at mount time, and the node builds the weed mount options from it.
+var volumeParameters = map[string]volumeParameter{
+	// resolved by CreateVolume
+	"path":                {validate: checkAbsolutePath},
+	"parentDir":           {validate: checkAbsolutePath},
+	"volumeName":          {},
+	volumeCapacityKey:     {validate: checkNonNegativeInt},
+	sharedDirParam:        {validate: checkAbsolutePath},
+	pathPatternParam:      {validate: checkPathPattern},
+	deletePolicyParam:     {validate: checkOneOf(deletePolicyDelete, deletePolicyTrash)},
+	deleteCollectionParam: {validate: checkBool},
+
+	// storage options
+	"collection":       {mountArg: "collection"},
+	"replication":      {validate: checkReplication, mountArg: "replication"},
+	"ttl":              {validate: checkTtl, mountArg: "ttl"},
+	"diskType":         {validate: checkDiskType, mountArg: "disk"},
+	"disk":             {validate: checkDiskType, mountArg: "disk"},
+	"dataCenter":       {mountArg: "dataCenter"},
+	"dataLocality":     {validate: checkDataLocalityName},
+	"chunkSizeLimitMB": {validate: checkPositiveInt, mountArg: "chunkSizeLimitMB"},
+	// derived from the capacity by the node rather than passed on
+	"collectionQuotaMB": {validate: checkNonNegativeInt},
+
+	// mount options
+	"volumeServerAccess": {validate: checkOneOf("direct", "publicUrl", "filerProxy"), mountArg: "volumeServerAccess"},
+	"readRetryTime":      {validate: checkDuration, mountArg: "readRetryTime"},
+	"uidMap":             {validate: checkIdMap, mountArg: "map.uid"},
+	"gidMap":             {validate: checkIdMap, mountArg: "map.gid"},
+	"map.uid":            {validate: checkIdMap, mountArg: "map.uid"},
+	"map.gid":            {validate: checkIdMap, mountArg: "map.gid"},
+	"filerUid":           {validate: checkNonNegativeInt},
+	"filerGid":           {validate: checkNonNegativeInt},
+	"cacheCapacityMB":    {validate: checkNonNegativeInt, mountArg: "cacheCapacityMB"},
+	volumeCacheDirKey:    {validate: checkAbsolutePath},
+	"cacheMetaTtlSec":    {validate: checkNonNegativeInt, mountArg: "cacheMetaTtlSec"},
+	"concurrentReaders":  {validate: checkNonNegativeInt, mountArg: "concurrentReaders"},
+	"concurrentWriters":  {validate: checkNonNegativeInt, mountArg: "concurrentWriters"},
+	"allowOthers":        {validate: checkBool, mountArg: "allowOthers"},
+	"nonempty":           {validate: checkBool, mountArg: "nonempty"},
+	filerParam:           {mountArg: "filer"},
+	filerTlsConfigParam:  {},
+	"filer.path":         {validate: checkAbsolutePath, mountArg: "filer.path"},
+
+	// resource limits of the weed mount process
+	memoryLimitParam: {validate: checkNonNegativeInt},
+	cpuLimitParam:    {validate: checkCPULimit},
+}
+
+// Prefixes of the parameters and attributes added by Kubernetes components,
+// such as the PVC name or the provisioner identity.
+var orchestratorParameterPrefixes = []string{
+	"csi.storage.k8s.io/",
+	"storage.kubernetes.io/",
+}
+
+// validateVolumeParameters checks params against volumeParameters.
+func validateVolumeParameters(params map[string]string) error {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		param, known := volumeParameters[key]
+		if !known {
+			if hasOrchestratorPrefix(key) {
+				continue
+			}
+			return fmt.Errorf("unknown parameter %q", key)
+		}
+		if param.validate == nil || params[key] == "" {
+			continue
+		}
+		if err := param.validate(params[key]); err != nil {
+			return fmt.Errorf("invalid parameter %s=%q: %v", key, params[key], err)
+		}
+	}
+
+	if err := checkVolumeCluster(params); err != nil {
+		return err
+	}
+	return checkVolumeDataLocality(params)
+}
+
+// checkVolumeContext is validateVolumeParameters for the volume context of a
+// volume being staged. Unknown keys are only logged: volumes provisioned
+// before the schema existed may carry keys the node has always ignored.
+func checkVolumeContext(volumeID string, volContext map[string]string) error {
+	known := make(map[string]string, len(volContext))
+	for key, value := range volContext {
+		if _, ok := volumeParameters[key]; !ok && !hasOrchestratorPrefix(key) {
+			glog.Warningf("volume %s: ignoring unknown volume attribute %q", volumeID, key)
+			continue
+		}
+		known[key] = value
+	}
+	return validateVolumeParameters(known)
+}
+
+func hasOrchestratorPrefix(key string) bool {
+	for _, prefix := range orchestratorParameterPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkAbsolutePath(value string) error {
+	if !path.IsAbs(value) {
+		return fmt.Errorf("must be an absolute path")
+	}
+	return nil
+}
+
+func checkNonNegativeInt(value string) error {
+	if n, err := strconv.ParseInt(value, 10, 64); err != nil || n < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	return nil
+}
+
+// minCPULimitMillicores is the smallest CPU limit of a weed mount process:
+// the kernel rejects cpu.max quotas below 1000µs, a hundredth of the period
+// the mount service uses.
+const minCPULimitMillicores = 10
+
+func checkCPULimit(value string) error {
+	if n, err := strconv.ParseInt(value, 10, 64); err != nil || n < 0 || (n > 0 && n < minCPULimitMillicores) {
+		return fmt.Errorf("must be 0 for no limit or at least %d", minCPULimitMillicores)
+	}
+	return nil
+}
+
+func checkPositiveInt(value string) error {
+	if n, err := strconv.ParseInt(value, 10, 64); err != nil || n <= 0 {
+		return fmt.Errorf("must be a positive integer")
+	}
+	return nil
+}
+
+func checkReplication(value string) error {
+	if _, err := replicaCopies(value); err != nil {
+		return fmt.Errorf("must be three digits such as \"001\"")
+	}
+	return nil
+}
+
+var ttlPattern = regexp.MustCompile(`^[0-9]+[mhdwMy]?$`)
+
+func checkTtl(value string) error {
+	if !ttlPattern.MatchString(value) {
+		return fmt.Errorf("must be a number followed by one of m, h, d, w, M, y such as \"3d\"")
+	}
+	return nil
+}
+
+var diskTypePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func checkDiskType(value string) error {
+	if !diskTypePattern.MatchString(value) {
+		return fmt.Errorf("must be a disk type such as \"hdd\" or \"ssd\"")
+	}
+	return nil
+}
+
+func checkDataLocalityName(value string) error {
+	if _, ok := datalocality.FromString(value); !ok {
+		return fmt.Errorf("unknown data locality")
+	}
+	return nil
+}
+
+func checkDuration(value string) error {
+	if _, err := time.ParseDuration(value); err != nil {
+		return fmt.Errorf("must be a duration such as \"6s\"")
+	}
+	return nil
+}
+
+var idMapPattern = regexp.MustCompile(`^[0-9]+:[0-9]+(,[0-9]+:[0-9]+)*$`)
+
+func checkIdMap(value string) error {
+	if !idMapPattern.MatchString(value) {
+		return fmt.Errorf("must be comma-separated <local_id>:<filer_id> pairs")
+	}
+	return nil
+}
+
+func checkBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("must be true or false")
+	}
+	return nil
+}
+
+func checkOneOf(allowed ...string) parameterValidator {
+	return func(value string) error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+	}
+}
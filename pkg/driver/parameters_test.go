@@ -0,0 +1,76 @@
+package driver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateVolumeParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		errMsg string
+	}{
+		{"empty", nil, ""},
+		{"valid", map[string]string{
+			"replication":        "001",
+			"ttl":                "3d",
+			"diskType":           "ssd",
+			"dataLocality":       "write_preferLocalDc",
+			"chunkSizeLimitMB":   "4",
+			"readRetryTime":      "6s",
+			"volumeServerAccess": "filerProxy",
+			"uidMap":             "1000:0,1001:1",
+			"path":               "/data/vol",
+			memoryLimitParam:     "1024",
+			cpuLimitParam:        "500",
+		}, ""},
+		{"orchestrator keys", map[string]string{
+			pvcNameParam: "data",
+			"storage.kubernetes.io/csiProvisionerIdentity": "1234-seaweedfs-csi-driver",
+		}, ""},
+		{"empty values are unset", map[string]string{"ttl": ""}, ""},
+		{"typo", map[string]string{"replicaton": "001"}, `unknown parameter "replicaton"`},
+		{"replication", map[string]string{"replication": "01"}, "invalid parameter replication"},
+		{"ttl", map[string]string{"ttl": "3days"}, "invalid parameter ttl"},
+		{"diskType", map[string]string{"diskType": "s s d"}, "invalid parameter diskType"},
+		{"dataLocality", map[string]string{"dataLocality": "nearby"}, "invalid parameter dataLocality"},
+		{"chunkSizeLimitMB", map[string]string{"chunkSizeLimitMB": "0"}, "invalid parameter chunkSizeLimitMB"},
+		{"readRetryTime", map[string]string{"readRetryTime": "6"}, "invalid parameter readRetryTime"},
+		{"volumeServerAccess", map[string]string{"volumeServerAccess": "proxy"}, "must be one of direct, publicUrl, filerProxy"},
+		{"memoryLimitMB", map[string]string{memoryLimitParam: "-1"}, "invalid parameter memoryLimitMB"},
+		{"cpuLimitMillicores", map[string]string{cpuLimitParam: "half"}, "invalid parameter cpuLimitMillicores"},
+		{"cpuLimitMillicores below the cpu.max minimum", map[string]string{cpuLimitParam: "9"}, "invalid parameter cpuLimitMillicores"},
+		{"relative path", map[string]string{"parentDir": "buckets"}, "invalid parameter parentDir"},
+		{"tls without filer", map[string]string{filerTlsConfigParam: "grpc.cluster_b"}, "requires filer"},
+		{"strict locality", map[string]string{"dataLocality": "write_onlyLocalDc"}, "unknown data locality"},
+		{"rack locality", map[string]string{"dataLocality": "write_preferLocalRack"}, "unknown data locality"},
+	}
+	for _, tt := range tests {
+		err := validateVolumeParameters(tt.params)
+		if tt.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s: got error %v, want one containing %q", tt.name, err, tt.errMsg)
+		}
+	}
+}
+
+func TestCheckVolumeContextOnlyWarnsAboutUnknownKeys(t *testing.T) {
+	volContext := map[string]string{"legacyOption": "x", "replication": "001"}
+	if err := checkVolumeContext("/buckets/pvc-1", volContext); err != nil {
+		t.Fatalf("checkVolumeContext with an unknown key: %v", err)
+	}
+	if _, _, err := applyMountFlags(volContext, []string{"concurrentWriters=32"}); err != nil {
+		t.Fatalf("applyMountFlags with an unknown attribute: %v", err)
+	}
+
+	volContext["replication"] = "01"
+	if err := checkVolumeContext("/buckets/pvc-1", volContext); err == nil || !strings.Contains(err.Error(), "invalid parameter replication") {
+		t.Fatalf("checkVolumeContext with a malformed value = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package driver
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Snapshots are full copies of a volume directory stored at
+// /.snapshots/<snapshot name>. Snapshot names are unique, so unlike volume
+// names they cannot collide across parent directories. The snapshot path is
+// used as the snapshot ID; the snapshot directory's Extended attributes
+// record the source volume and whether the copy has completed.
+const (
+	snapshotsDir = "/.snapshots"
+
+	snapshotSourceVolumeKey = csiExtendedPrefix + "snapshot.sourceVolumeId"
+	snapshotCreationTimeKey = csiExtendedPrefix + "snapshot.creationTime"
+	snapshotSizeKey         = csiExtendedPrefix + "snapshot.size"
+	snapshotReadyKey        = csiExtendedPrefix + "snapshot.ready"
+)
+
+type snapshotInfo struct {
+	snapshotId     string
+	sourceVolumeId string
+	creationTime   time.Time
+	sizeBytes      int64
+	readyToUse     bool
+}
+
+func (s *snapshotInfo) toCSI() *csi.Snapshot {
+	return &csi.Snapshot{
+		SnapshotId:     s.snapshotId,
+		SourceVolumeId: s.sourceVolumeId,
+		CreationTime:   timestamppb.New(s.creationTime),
+		SizeBytes:      s.sizeBytes,
+		ReadyToUse:     s.readyToUse,
+	}
+}
+
+// snapshotFromEntry reads the snapshot stored at entry in dir. It returns nil
+// if the entry is not a snapshot directory.
+func snapshotFromEntry(dir string, entry *filer_pb.Entry) *snapshotInfo {
+	if !entry.IsDirectory {
+		return nil
+	}
+	sourceVolumeId := string(entry.Extended[snapshotSourceVolumeKey])
+	if sourceVolumeId == "" {
+		return nil
+	}
+
+	info := &snapshotInfo{
+		snapshotId:     path.Join(dir, entry.Name),
+		sourceVolumeId: sourceVolumeId,
+		readyToUse:     string(entry.Extended[snapshotReadyKey]) == "true",
+	}
+	if t, err := time.Parse(time.RFC3339Nano, string(entry.Extended[snapshotCreationTimeKey])); err == nil {
+		info.creationTime = t
+	}
+	if size, err := strconv.ParseInt(string(entry.Extended[snapshotSizeKey]), 10, 64); err == nil {
+		info.sizeBytes = size
+	}
+	return info
+}
+
+// parseSnapshotId splits a snapshot ID into its directory and name. It
+// returns false for IDs that do not point inside snapshotsDir, so a bogus ID
+// can never be used to remove anything else.
+func parseSnapshotId(snapshotId string) (dir, name string, ok bool) {
+	cleaned := path.Clean(snapshotId)
+	if cleaned != snapshotId || path.Dir(cleaned) != snapshotsDir {
+		return "", "", false
+	}
+	return path.Dir(cleaned), path.Base(cleaned), true
+}
+
+func lookupSnapshot(ctx context.Context, client filer_pb.FilerClient, snapshotId string) (*snapshotInfo, error) {
+	dir, name, ok := parseSnapshotId(snapshotId)
+	if !ok {
+		return nil, nil
+	}
+	entry, err := lookupEntry(ctx, client, dir, name)
+	if err == errEntryNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return snapshotFromEntry(dir, entry), nil
+}
+
+// completeSnapshot marks the snapshot snapshotId ready, with the size of its
+// copied content.
+func completeSnapshot(ctx context.Context, client filer_pb.FilerClient, snapshotId string, sizeBytes int64) error {
+	dir, name, _ := parseSnapshotId(snapshotId)
+	entry, err := lookupEntry(ctx, client, dir, name)
+	if err != nil {
+		return err
+	}
+	if entry.Extended == nil {
+		entry.Extended = make(map[string][]byte)
+	}
+	entry.Extended[snapshotSizeKey] = []byte(strconv.FormatInt(sizeBytes, 10))
+	entry.Extended[snapshotReadyKey] = []byte("true")
+	return updateEntry(ctx, client, dir, entry)
+}
+
+// listSnapshots returns the snapshots taken of sourceVolumeId, or every
+// snapshot if sourceVolumeId is empty.
+func listSnapshots(ctx context.Context, client filer_pb.FilerClient, sourceVolumeId string) ([]*snapshotInfo, error) {
+	entries, err := listEntries(ctx, client, snapshotsDir)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*snapshotInfo
+	for _, entry := range entries {
+		snapshot := snapshotFromEntry(snapshotsDir, entry)
+		if snapshot == nil {
+			continue
+		}
+		if sourceVolumeId != "" && snapshot.sourceVolumeId != sourceVolumeId {
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
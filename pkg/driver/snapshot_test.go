@@ -0,0 +1,112 @@
+package driver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseSnapshotId(t *testing.T) {
+	tests := []struct {
+		snapshotId string
+		dir, name  string
+		ok         bool
+	}{
+		{"/.snapshots/snapshot-1", "/.snapshots", "snapshot-1", true},
+		{"/.snapshots", "", "", false},
+		{"/.snapshots/snapshot-1/data", "", "", false},
+		{"/.snapshots/../buckets/pvc-2", "", "", false},
+		{"/buckets/snapshot-1", "", "", false},
+		{"snapshot-1", "", "", false},
+	}
+	for _, tt := range tests {
+		dir, name, ok := parseSnapshotId(tt.snapshotId)
+		if dir != tt.dir || name != tt.name || ok != tt.ok {
+			t.Errorf("parseSnapshotId(%q) = %q, %q, %v; want %q, %q, %v", tt.snapshotId, dir, name, ok, tt.dir, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestSnapshotFromEntry(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entry := &filer_pb.Entry{
+		Name:        "snapshot-1",
+		IsDirectory: true,
+		Extended: map[string][]byte{
+			snapshotSourceVolumeKey: []byte("/buckets/pvc-1"),
+			snapshotCreationTimeKey: []byte(created.Format(time.RFC3339Nano)),
+			snapshotSizeKey:         []byte("1024"),
+			snapshotReadyKey:        []byte("true"),
+		},
+	}
+
+	snapshot := snapshotFromEntry(snapshotsDir, entry)
+	if snapshot == nil {
+		t.Fatal("expected a snapshot")
+	}
+	if snapshot.snapshotId != "/.snapshots/snapshot-1" {
+		t.Errorf("snapshotId = %q", snapshot.snapshotId)
+	}
+	if snapshot.sourceVolumeId != "/buckets/pvc-1" {
+		t.Errorf("sourceVolumeId = %q", snapshot.sourceVolumeId)
+	}
+	if !snapshot.creationTime.Equal(created) {
+		t.Errorf("creationTime = %v, want %v", snapshot.creationTime, created)
+	}
+	if snapshot.sizeBytes != 1024 || !snapshot.readyToUse {
+		t.Errorf("sizeBytes = %d, readyToUse = %v", snapshot.sizeBytes, snapshot.readyToUse)
+	}
+}
+
+func TestSnapshotFromEntryIgnoresOtherDirectories(t *testing.T) {
+	if snapshot := snapshotFromEntry(snapshotsDir, &filer_pb.Entry{Name: "data", IsDirectory: true}); snapshot != nil {
+		t.Fatalf("expected no snapshot, got %+v", snapshot)
+	}
+}
+
+func TestCloneEntryMetadataDropsDriverAttributes(t *testing.T) {
+	entry := &filer_pb.Entry{
+		Name:        "pvc-1",
+		IsDirectory: true,
+		Attributes:  &filer_pb.FuseAttributes{FileMode: 0755, Inode: 42},
+		Extended: map[string][]byte{
+			snapshotSourceVolumeKey: []byte("/buckets/pvc-1"),
+			"xattr-user.comment":    []byte("keep me"),
+		},
+	}
+
+	clone := cloneEntryMetadata(entry)
+	if _, ok := clone.Extended[snapshotSourceVolumeKey]; ok {
+		t.Error("driver attribute was copied")
+	}
+	if string(clone.Extended["xattr-user.comment"]) != "keep me" {
+		t.Error("user attribute was not copied")
+	}
+	if clone.Attributes.Inode != 0 || clone.Attributes.FileMode != 0755 {
+		t.Errorf("unexpected attributes %+v", clone.Attributes)
+	}
+	if entry.Attributes.Inode != 42 {
+		t.Error("source entry was modified")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	start, end, next, err := paginate(5, "", 2)
+	if err != nil || start != 0 || end != 2 || next != "2" {
+		t.Fatalf("first page = %d, %d, %q, %v", start, end, next, err)
+	}
+	start, end, next, err = paginate(5, next, 2)
+	if err != nil || start != 2 || end != 4 || next != "4" {
+		t.Fatalf("second page = %d, %d, %q, %v", start, end, next, err)
+	}
+	start, end, next, err = paginate(5, next, 2)
+	if err != nil || start != 4 || end != 5 || next != "" {
+		t.Fatalf("last page = %d, %d, %q, %v", start, end, next, err)
+	}
+	if _, _, _, err := paginate(5, "abc", 0); status.Code(err) != codes.Aborted {
+		t.Fatalf("expected Aborted for invalid token, got %v", err)
+	}
+}
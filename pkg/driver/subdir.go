@@ -0,0 +1,92 @@
+package driver
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Parameters of the subdirectory provisioning mode, in which volumes are
+// directories inside an existing shared directory instead of buckets of
+// their own.
+const (
+	sharedDirParam   = "sharedDir"
+	pathPatternParam = "pathPattern"
+)
+
+// pathPatternPlaceholders map the placeholders of a pathPattern to the
+// CreateVolume parameters they are replaced with. ${.PV.name} is handled
+// separately as it comes from the request name.
+var pathPatternPlaceholders = map[string]string{
+	"${.PVC.namespace}": pvcNamespaceParam,
+	"${.PVC.name}":      pvcNameParam,
+}
+
+const pvNamePlaceholder = "${.PV.name}"
+
+// expandPathPattern returns the path of the volume requested as name
+// relative to its shared directory. Without a pattern the volume directory
+// is named after the volume.
+func expandPathPattern(pattern, name string, params map[string]string) (string, error) {
+	if pattern == "" {
+		pattern = pvNamePlaceholder
+	}
+	expanded := strings.ReplaceAll(pattern, pvNamePlaceholder, name)
+	for placeholder, param := range pathPatternPlaceholders {
+		if !strings.Contains(expanded, placeholder) {
+			continue
+		}
+		value := params[param]
+		if value == "" {
+			return "", fmt.Errorf("%s needs %s, run the external-provisioner with --extra-create-metadata", placeholder, param)
+		}
+		expanded = strings.ReplaceAll(expanded, placeholder, value)
+	}
+	if err := checkRelativeSubPath(expanded); err != nil {
+		return "", fmt.Errorf("%s=%q expands to %q: %v", pathPatternParam, pattern, expanded, err)
+	}
+	return path.Clean(expanded), nil
+}
+
+// checkPathPattern validates a pathPattern parameter.
+func checkPathPattern(value string) error {
+	_, err := expandPathPattern(value, "pv", map[string]string{
+		pvcNamespaceParam: "namespace",
+		pvcNameParam:      "pvc",
+	})
+	return err
+}
+
+// checkRelativeSubPath checks that p names a directory below, and not at or
+// above, the directory it is relative to.
+func checkRelativeSubPath(p string) error {
+	if strings.Contains(p, "${") {
+		return fmt.Errorf("unknown placeholder")
+	}
+	if path.IsAbs(p) {
+		return fmt.Errorf("must be a relative path")
+	}
+	cleaned := path.Clean(p)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return fmt.Errorf("must be a path below the shared directory")
+	}
+	return nil
+}
+
+// sharesCollection reports whether the volume of volContext lives in a
+// shared directory, and so writes to a collection other volumes write to as
+// well. Collection quotas would hold each such volume to the usage of all.
+func sharesCollection(volContext map[string]string) bool {
+	return volContext[sharedDirParam] != ""
+}
+
+// sharedDirCollection returns the collection of the volume mounted at
+// sharedDir, which the volumes inside it write to as well.
+func sharedDirCollection(sharedDir, bucketsDir string) string {
+	if rel, ok := strings.CutPrefix(sharedDir, bucketsDir+"/"); ok {
+		// everything below a bucket belongs to the bucket's collection
+		bucket, _, _ := strings.Cut(rel, "/")
+		return bucket
+	}
+	return path.Base(sharedDir)
+}
@@ -0,0 +1,52 @@
+package driver
+
+import "testing"
+
+func TestExpandPathPattern(t *testing.T) {
+	params := map[string]string{
+		pvcNamespaceParam: "team-a",
+		pvcNameParam:      "data",
+	}
+	tests := []struct {
+		pattern string
+		params  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"", params, "pvc-1234", false},
+		{"${.PVC.namespace}/${.PVC.name}", params, "team-a/data", false},
+		{"${.PVC.namespace}-${.PVC.name}-${.PV.name}", params, "team-a-data-pvc-1234", false},
+		{"static/./${.PV.name}", params, "static/pvc-1234", false},
+		{"${.PVC.namespace}/${.PVC.name}", nil, "", true},
+		{"../${.PV.name}", params, "", true},
+		{"/abs/${.PV.name}", params, "", true},
+		{".", params, "", true},
+		{"${.PVC.annotations.x}", params, "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandPathPattern(tt.pattern, "pvc-1234", tt.params)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("expandPathPattern(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("expandPathPattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSharedDirCollection(t *testing.T) {
+	tests := map[string]string{
+		"/buckets/shared":        "shared",
+		"/buckets/shared/team-a": "shared",
+		"/data/shared":           "shared",
+		"/s3/shared/team-a":      "team-a",
+	}
+	for sharedDir, want := range tests {
+		if got := sharedDirCollection(sharedDir, "/buckets"); got != want {
+			t.Fatalf("sharedDirCollection(%q) = %q, want %q", sharedDir, got, want)
+		}
+	}
+	if got := sharedDirCollection("/s3/shared/team-a", "/s3"); got != "shared" {
+		t.Fatalf("sharedDirCollection in a custom buckets directory = %q, want shared", got)
+	}
+}
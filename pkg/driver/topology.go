@@ -0,0 +1,49 @@
+package driver
+
+import (
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+// Topology segment keys used in NodeGetInfo and accessibility requirements.
+const (
+	topologyDataCenterKey = "topology.seaweedfs.com/dataCenter"
+	topologyRackKey       = "topology.seaweedfs.com/rack"
+)
+
+// nodeTopology returns the topology of a node in dataCenter and rack, or nil
+// if the node's location is not configured. A rack is only meaningful
+// within its data center.
+func nodeTopology(dataCenter, rack string) *csi.Topology {
+	if dataCenter == "" {
+		return nil
+	}
+	segments := map[string]string{topologyDataCenterKey: dataCenter}
+	if rack != "" {
+		segments[topologyRackKey] = rack
+	}
+	return &csi.Topology{Segments: segments}
+}
+
+// topologyDataCenter returns the data center a topology is restricted to, or
+// an empty string if it spans every data center.
+func topologyDataCenter(topology *csi.Topology) string {
+	return topology.GetSegments()[topologyDataCenterKey]
+}
+
+// preferredDataCenter returns the data center a new volume's writes should
+// go to: that of the first preferred topology, else of the first requisite
+// one. With WaitForFirstConsumer binding, the first preferred topology is
+// that of the node the consuming pod is scheduled to.
+func preferredDataCenter(requirement *csi.TopologyRequirement) string {
+	for _, topology := range requirement.GetPreferred() {
+		if dataCenter := topologyDataCenter(topology); dataCenter != "" {
+			return dataCenter
+		}
+	}
+	for _, topology := range requirement.GetRequisite() {
+		if dataCenter := topologyDataCenter(topology); dataCenter != "" {
+			return dataCenter
+		}
+	}
+	return ""
+}
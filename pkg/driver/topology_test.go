@@ -0,0 +1,45 @@
+package driver
+
+import (
+	"testing"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+)
+
+func TestNodeTopology(t *testing.T) {
+	if topology := nodeTopology("", "r1"); topology != nil {
+		t.Errorf("expected no topology without a data center, got %v", topology)
+	}
+
+	topology := nodeTopology("dc1", "")
+	if len(topology.Segments) != 1 || topology.Segments[topologyDataCenterKey] != "dc1" {
+		t.Errorf("unexpected topology %v", topology)
+	}
+
+	topology = nodeTopology("dc1", "r1")
+	if topology.Segments[topologyDataCenterKey] != "dc1" || topology.Segments[topologyRackKey] != "r1" {
+		t.Errorf("unexpected topology %v", topology)
+	}
+}
+
+func TestPreferredDataCenter(t *testing.T) {
+	dc := func(name string) *csi.Topology {
+		return &csi.Topology{Segments: map[string]string{topologyDataCenterKey: name}}
+	}
+
+	tests := []struct {
+		name        string
+		requirement *csi.TopologyRequirement
+		want        string
+	}{
+		{"no requirement", nil, ""},
+		{"preferred first", &csi.TopologyRequirement{Requisite: []*csi.Topology{dc("dc1"), dc("dc2")}, Preferred: []*csi.Topology{dc("dc2"), dc("dc1")}}, "dc2"},
+		{"requisite only", &csi.TopologyRequirement{Requisite: []*csi.Topology{dc("dc1")}}, "dc1"},
+		{"foreign segments", &csi.TopologyRequirement{Preferred: []*csi.Topology{{Segments: map[string]string{"zone": "a"}}}}, ""},
+	}
+	for _, tt := range tests {
+		if got := preferredDataCenter(tt.requirement); got != tt.want {
+			t.Errorf("%s: preferredDataCenter = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+)
+
+// deletePolicyParam selects what DeleteVolume does with the volume
+// directory: remove it, or move it to the trash directory of the cluster
+// from which the trash reaper removes it after the retention period.
+const (
+	deletePolicyParam  = "deletePolicy"
+	deletePolicyDelete = "delete"
+	deletePolicyTrash  = "trash"
+)
+
+const (
+	defaultTrashDir = "/.csi-trash"
+	// trashTimeFormat prefixes the names of trash entries with the time
+	// they were deleted at
+	trashTimeFormat   = "20060102T150405Z"
+	trashReapInterval = time.Hour
+)
+
+func (d *SeaweedFsDriver) trashDir() string {
+	if d.TrashDir == "" {
+		return defaultTrashDir
+	}
+	return d.TrashDir
+}
+
+// trashEntryName returns the name of the trash entry of a volume deleted at
+// deletedAt.
+func trashEntryName(volumeName string, deletedAt time.Time) string {
+	return deletedAt.UTC().Format(trashTimeFormat) + "-" + volumeName
+}
+
+// parseTrashEntryName returns the time the trash entry name was deleted at
+// and the name of the volume, or false if name is not a trash entry.
+func parseTrashEntryName(name string) (time.Time, string, bool) {
+	prefix, volumeName, ok := strings.Cut(name, "-")
+	if !ok || volumeName == "" {
+		return time.Time{}, "", false
+	}
+	deletedAt, err := time.Parse(trashTimeFormat, prefix)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return deletedAt, volumeName, true
+}
+
+// moveToTrash moves the volume directory into trashDir and returns the path
+// of the trash entry.
+func moveToTrash(ctx context.Context, client filer_pb.FilerClient, trashDir, parentDir, volumeName string, now time.Time) (string, error) {
+	if err := ensureDir(ctx, client, trashDir); err != nil {
+		return "", fmt.Errorf("create trash directory %s: %v", trashDir, err)
+	}
+	trashName := trashEntryName(volumeName, now)
+	err := client.WithFilerClient(false, func(c filer_pb.SeaweedFilerClient) error {
+		_, err := c.AtomicRenameEntry(ctx, &filer_pb.AtomicRenameEntryRequest{
+			OldDirectory: parentDir,
+			OldName:      volumeName,
+			NewDirectory: trashDir,
+			NewName:      trashName,
+		})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return path.Join(trashDir, trashName), nil
+}
+
+// ensureDir creates the directory dir if it does not exist yet.
+func ensureDir(ctx context.Context, client filer_pb.FilerClient, dir string) error {
+	parentDir, name := path.Dir(dir), path.Base(dir)
+	_, err := lookupEntry(ctx, client, parentDir, name)
+	if err != errEntryNotFound {
+		return err
+	}
+	if err := filer_pb.Mkdir(ctx, client, parentDir, name, nil); err != nil {
+		// lost a race with another delete creating it
+		if _, lookupErr := lookupEntry(ctx, client, parentDir, name); lookupErr == nil {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// purgeTrash removes the entries of trashDir deleted longer than retention
+// ago, along with the collections they own. Entries that were not put there
+// by moveToTrash are left alone.
+func purgeTrash(ctx context.Context, client *SeaweedFsDriver, trashDir string, retention time.Duration, now time.Time) error {
+	entries, err := listEntries(ctx, client, trashDir)
+	if err != nil {
+		return fmt.Errorf("list %s: %w", trashDir, err)
+	}
+	for _, entry := range entries {
+		deletedAt, volumeName, ok := parseTrashEntryName(entry.Name)
+		if !ok || now.Sub(deletedAt) < retention {
+			continue
+		}
+		entryPath := path.Join(trashDir, entry.Name)
+		deleteData := true
+		if collection, err := trashedCollection(ctx, client, entry, volumeName); err != nil {
+			return fmt.Errorf("collection of %s: %v", entryPath, err)
+		} else if collection != "" {
+			if err := dropCollection(ctx, client, collection); err != nil {
+				return fmt.Errorf("delete collection %s of %s: %v", collection, entryPath, err)
+			}
+			glog.Infof("deleted collection %s of %s", collection, entryPath)
+			deleteData = false
+		}
+		if err := filer_pb.Remove(ctx, client, trashDir, entry.Name, deleteData, true, true, false, nil); err != nil {
+			return fmt.Errorf("remove %s: %v", entryPath, err)
+		}
+		glog.Infof("purged %s from trash, deleted at %s", entryPath, deletedAt)
+	}
+	return nil
+}
+
+// trashedCollection returns the collection owned by the trashed volume
+// entry, unless a volume of the same name has been created since.
+func trashedCollection(ctx context.Context, client *SeaweedFsDriver, entry *filer_pb.Entry, volumeName string) (string, error) {
+	meta, err := readVolumeMetadata(entry)
+	if err != nil || meta == nil {
+		// nothing to tell whether the collection is owned
+		return "", nil
+	}
+	bucketsDir, err := client.filerBucketsDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	collection := ownedCollection(meta.Parameters, bucketsDir, volumeName)
+	if collection == "" {
+		return "", nil
+	}
+	if _, err := lookupEntry(ctx, client, bucketsDir, volumeName); err != errEntryNotFound {
+		return "", err
+	}
+	return collection, nil
+}
+
+// startTrashReaper periodically purges the expired entries of the trash
+// directory of the driver's cluster. Trash in other clusters is purged when
+// volumes are deleted there.
+func (cs *ControllerServer) startTrashReaper(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		glog.Infof("trash reaper started with interval %v, retention %v", interval, cs.Driver.TrashRetention)
+		for {
+			cs.runTrashReaperTick()
+			select {
+			case <-ticker.C:
+			case <-cs.stopCh:
+				glog.Infof("trash reaper stopped")
+				return
+			}
+		}
+	}()
+}
+
+// runTrashReaperTick runs one purge with panic recovery, like the health
+// monitor of the node server.
+func (cs *ControllerServer) runTrashReaperTick() {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Errorf("trash reaper: recovered from panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	if err := purgeTrash(ctx, cs.Driver, cs.Driver.trashDir(), cs.Driver.TrashRetention, time.Now()); err != nil {
+		glog.Warningf("trash reaper: %v", err)
+	}
+}
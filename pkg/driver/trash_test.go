@@ -0,0 +1,35 @@
+package driver
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrashEntryName(t *testing.T) {
+	deletedAt := time.Date(2026, 10, 17, 15, 4, 5, 0, time.FixedZone("CEST", 2*3600))
+	name := trashEntryName("pvc-1234", deletedAt)
+	if name != "20261017T130405Z-pvc-1234" {
+		t.Fatalf("trashEntryName = %q", name)
+	}
+	got, volumeName, ok := parseTrashEntryName(name)
+	if !ok || !got.Equal(deletedAt) || volumeName != "pvc-1234" {
+		t.Fatalf("parseTrashEntryName(%q) = %v, %q, %v, want %v, pvc-1234", name, got, volumeName, ok, deletedAt)
+	}
+}
+
+func TestParseTrashEntryNameIgnoresForeignEntries(t *testing.T) {
+	for _, name := range []string{"pvc-1234", "notes.txt", "2026-10-17-pvc", "-pvc", "20261017T130405Z-"} {
+		if _, _, ok := parseTrashEntryName(name); ok {
+			t.Errorf("parseTrashEntryName(%q) accepted a name not written by moveToTrash", name)
+		}
+	}
+}
+
+func TestTrashDirDefault(t *testing.T) {
+	if dir := (&SeaweedFsDriver{}).trashDir(); dir != defaultTrashDir {
+		t.Fatalf("trashDir() = %q, want %q", dir, defaultTrashDir)
+	}
+	if dir := (&SeaweedFsDriver{TrashDir: "/trash"}).trashDir(); dir != "/trash" {
+		t.Fatalf("trashDir() = %q, want /trash", dir)
+	}
+}
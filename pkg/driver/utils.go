@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -16,34 +17,43 @@ import (
 	"github.com/seaweedfs/seaweedfs/weed/glog"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"k8s.io/mount-utils"
 )
 
 func NewNodeServer(n *SeaweedFsDriver) *NodeServer {
-	if n.CacheDir != "" {
-		cleanCacheDir := filepath.Clean(n.CacheDir)
-		cleanTempDir := filepath.Clean(os.TempDir())
-		if cleanCacheDir != cleanTempDir {
-			if err := removeDirContent(cleanCacheDir); err != nil {
-				glog.Warningf("error cleaning up cache dir %s: %v", cleanCacheDir, err)
-			}
-		}
-	}
+	n.cleanupCacheRoots()
 
 	ns := &NodeServer{
 		Driver:         n,
 		volumeMutexes:  NewKeyMutex(),
 		stopCh:         make(chan struct{}),
 		mounterFactory: newMounter,
-		capacityFn: func(volumeID string) (int64, error) {
+		capacityFn: func(volumeID string, credentials *mountmanager.MountCredentials) (int64, error) {
+			cluster, _, err := parseVolumeId(volumeID)
+			if err != nil {
+				return 0, err
+			}
+			client, err := n.forCredentials(cluster, credentials)
+			if err != nil {
+				return 0, err
+			}
+			capacity, err := volumeCapacityFromFiler(client, volumeID)
+			if err == nil {
+				return capacity, nil
+			}
+			glog.V(4).Infof("could not read capacity of volume %s from filer, asking Kubernetes: %v", volumeID, err)
 			return k8s.GetVolumeCapacity(n.name, volumeID)
 		},
 		isHealthyFn:      isStagingPathHealthy,
 		cleanupStagingFn: cleanupStaleStagingPath,
 		unmountFn:        mountutil.Unmount,
 		bindMountFn:      defaultBindMount,
+		volumeStatsFn:    getVolumeUsage,
 	}
 	ns.startHealthMonitor(defaultHealthCheckInterval)
+	ns.watchMountEvents()
 	return ns
 }
 
@@ -63,22 +73,20 @@ func GetLocalSocket(volumeSocketDir, volumeID string) string {
 }
 
 func CleanupVolumeResources(driver *SeaweedFsDriver, volumeID string) {
-	cacheDir := GetCacheDir(driver.CacheDir, volumeID)
-
-	// Validate that cacheDir is within cacheBase to prevent path traversal
-	cacheBase := driver.CacheDir
-	if cacheBase == "" {
-		cacheBase = os.TempDir()
-	}
-	cleanCacheBase := filepath.Clean(cacheBase)
-	cleanCacheDir := filepath.Clean(cacheDir)
-	rel, err := filepath.Rel(cleanCacheBase, cleanCacheDir)
-	if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
-		if err := os.RemoveAll(cleanCacheDir); err != nil {
-			glog.Warningf("failed to remove cache dir %s for volume %s: %v", cleanCacheDir, volumeID, err)
+	// the volume context may be gone, so look for the cache on every root
+	for _, cacheBase := range driver.cacheRoots() {
+		cacheDir := GetCacheDir(cacheBase, volumeID)
+
+		// Validate that cacheDir is within cacheBase to prevent path traversal
+		cleanCacheDir := filepath.Clean(cacheDir)
+		rel, err := filepath.Rel(cacheBase, cleanCacheDir)
+		if err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			if err := os.RemoveAll(cleanCacheDir); err != nil {
+				glog.Warningf("failed to remove cache dir %s for volume %s: %v", cleanCacheDir, volumeID, err)
+			}
+		} else {
+			glog.Warningf("skipping cache dir removal for volume %s: invalid path %s (rel: %s, err: %v)", volumeID, cleanCacheDir, rel, err)
 		}
-	} else {
-		glog.Warningf("skipping cache dir removal for volume %s: invalid path %s (rel: %s, err: %v)", volumeID, cleanCacheDir, rel, err)
 	}
 
 	localSocket := GetLocalSocket(driver.volumeSocketDir, volumeID)
@@ -96,7 +104,10 @@ func NewIdentityServer(d *SeaweedFsDriver) *IdentityServer {
 func NewControllerServer(d *SeaweedFsDriver) *ControllerServer {
 
 	return &ControllerServer{
-		Driver: d,
+		Driver:          d,
+		volumeMutexes:   NewKeyMutex(),
+		snapshotMutexes: NewKeyMutex(),
+		stopCh:          make(chan struct{}),
 	}
 }
 
@@ -150,22 +161,6 @@ func checkMount(targetPath string) (bool, error) {
 	return isMnt, nil
 }
 
-func removeDirContent(path string) error {
-	files, err := filepath.Glob(filepath.Join(path, "*"))
-	if err != nil {
-		return err
-	}
-
-	for _, file := range files {
-		err = os.RemoveAll(file)
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 type KeyMutex struct {
 	mutexes sync.Map
 }
@@ -190,3 +185,36 @@ func CheckDataLocality(dataLocality *datalocality.DataLocality, dataCenter *stri
 	}
 	return nil
 }
+
+// checkVolumeDataLocality validates the dataLocality requested for a volume
+// as far as it can be without knowing the node it will be mounted on.
+func checkVolumeDataLocality(params map[string]string) error {
+	value := params["dataLocality"]
+	if value == "" {
+		return nil
+	}
+	if err := checkDataLocalityName(value); err != nil {
+		return fmt.Errorf("dataLocality %q: %v", value, err)
+	}
+	return nil
+}
+
+// paginate selects the window [start, end) of a listing of total items for a
+// CSI list request and returns the token of the following page, if any.
+func paginate(total int, startingToken string, maxEntries int32) (start, end int, nextToken string, err error) {
+	if maxEntries < 0 {
+		return 0, 0, "", status.Error(codes.InvalidArgument, "max_entries must not be negative")
+	}
+	if startingToken != "" {
+		start, err = strconv.Atoi(startingToken)
+		if err != nil || start < 0 || start > total {
+			return 0, 0, "", status.Errorf(codes.Aborted, "invalid starting token %q", startingToken)
+		}
+	}
+	end = total
+	if maxEntries > 0 && start+int(maxEntries) < total {
+		end = start + int(maxEntries)
+		nextToken = strconv.Itoa(end)
+	}
+	return start, end, nextToken, nil
+}
@@ -31,6 +31,11 @@ type Volume struct {
 	publishPaths sync.Map          // targetPath (string) -> bool (readOnly)
 	volContext   map[string]string // volume context stored for re-staging
 	readOnly     bool              // FUSE-level readOnly flag
+	// credentials from the node-stage secrets, kept in memory only
+	credentials *mountmanager.MountCredentials
+	// stagedDevice is the device of the FUSE mount at StagedPath, which
+	// changes when the mount service restarts a crashed weed mount
+	stagedDevice string
 
 	// bindMountFn is used by Publish to perform the bind mount from the
 	// staging path to the pod-specific target path. Populated by the
@@ -101,6 +106,11 @@ func defaultBindMount(source, target string, readOnly bool) error {
 }
 
 func (vol *Volume) Quota(sizeByte int64) error {
+	if sharesCollection(vol.volContext) {
+		glog.V(4).Infof("volume %s shares its collection, not setting a quota", vol.VolumeId)
+		return nil
+	}
+
 	target := fmt.Sprintf("passthrough:///unix://%s", vol.localSocket)
 	dialOption := grpc.WithTransportCredentials(insecure.NewCredentials())
 
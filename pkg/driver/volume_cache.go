@@ -0,0 +1,126 @@
+package driver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+)
+
+// volumeCacheDirKey is the volume attribute selecting the cache root the
+// volume's chunk cache is placed on, one of the node's allowed roots.
+const volumeCacheDirKey = "cacheDir"
+
+// nodeLimit caps a per-volume mount option to a maximum set on the node.
+type nodeLimit struct {
+	max func(d *SeaweedFsDriver) int
+	// zeroIsUnlimited is set for options where 0 disables the limit, so
+	// that 0 is capped too
+	zeroIsUnlimited bool
+}
+
+var nodeLimits = map[string]nodeLimit{
+	"cacheCapacityMB":   {max: func(d *SeaweedFsDriver) int { return d.MaxCacheCapacityMB }},
+	"concurrentReaders": {max: func(d *SeaweedFsDriver) int { return d.MaxConcurrentReaders }},
+	"concurrentWriters": {max: func(d *SeaweedFsDriver) int { return d.MaxConcurrentWriters }, zeroIsUnlimited: true},
+}
+
+// cacheRoots returns the directories volume caches may be placed on, the
+// default cache directory first.
+func (d *SeaweedFsDriver) cacheRoots() []string {
+	defaultRoot := d.CacheDir
+	if defaultRoot == "" {
+		defaultRoot = os.TempDir()
+	}
+	roots := []string{filepath.Clean(defaultRoot)}
+	for _, root := range d.CacheRoots {
+		if root = filepath.Clean(root); !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+// cacheDirName matches the names GetCacheDir gives volume cache directories.
+var cacheDirName = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// cleanupCacheRoots removes the cache directories left behind by volumes that
+// are no longer mounted. Nothing is removed while the mount service cannot
+// tell which volumes are mounted.
+func (d *SeaweedFsDriver) cleanupCacheRoots() {
+	client, err := d.mountServiceClient()
+	if err != nil {
+		glog.Warningf("not cleaning up cache dirs: %v", err)
+		return
+	}
+	mounts, err := client.List()
+	if err != nil {
+		glog.Warningf("not cleaning up cache dirs: %v", err)
+		return
+	}
+	removeStaleCacheDirs(d.cacheRoots(), mounts)
+}
+
+// removeStaleCacheDirs removes the volume cache directories on roots that no
+// mount uses. Everything else on the roots, which may be shared, is left
+// alone.
+func removeStaleCacheDirs(roots []string, mounts []mountmanager.MountStatus) {
+	inUse := make(map[string]bool, len(mounts))
+	for _, mount := range mounts {
+		inUse[filepath.Clean(mount.CacheDir)] = true
+	}
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				glog.Warningf("error cleaning up cache dir %s: %v", root, err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			cacheDir := filepath.Join(root, entry.Name())
+			if !entry.IsDir() || !cacheDirName.MatchString(entry.Name()) || inUse[cacheDir] {
+				continue
+			}
+			if err := os.RemoveAll(cacheDir); err != nil {
+				glog.Warningf("error removing stale cache dir %s: %v", cacheDir, err)
+			} else {
+				glog.Infof("removed stale cache dir %s", cacheDir)
+			}
+		}
+	}
+}
+
+// volumeCacheBase returns the cache root of a volume staged with volContext.
+func (d *SeaweedFsDriver) volumeCacheBase(volContext map[string]string) (string, error) {
+	requested := volContext[volumeCacheDirKey]
+	roots := d.cacheRoots()
+	if requested == "" {
+		return roots[0], nil
+	}
+	if !slices.Contains(roots, filepath.Clean(requested)) {
+		return "", fmt.Errorf("cache directory %s is not one of the cache roots of node %s", requested, d.nodeID)
+	}
+	return filepath.Clean(requested), nil
+}
+
+// applyNodeLimits caps the options in argsMap to the maximums of the node.
+func (d *SeaweedFsDriver) applyNodeLimits(volumeID string, argsMap map[string]string) {
+	for key, limit := range nodeLimits {
+		maximum := limit.max(d)
+		if maximum <= 0 {
+			continue
+		}
+		value, err := strconv.Atoi(argsMap[key])
+		if err != nil || value > maximum || (value == 0 && limit.zeroIsUnlimited) {
+			glog.Warningf("volume %s: %s=%s exceeds the node maximum, using %d", volumeID, key, argsMap[key], maximum)
+			argsMap[key] = strconv.Itoa(maximum)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package driver
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager"
+)
+
+func TestVolumeCacheBase(t *testing.T) {
+	d := &SeaweedFsDriver{CacheDir: "/var/cache/seaweedfs", CacheRoots: []string{"/mnt/nvme/"}}
+
+	tests := []struct {
+		requested string
+		want      string
+		wantErr   bool
+	}{
+		{"", "/var/cache/seaweedfs", false},
+		{"/var/cache/seaweedfs", "/var/cache/seaweedfs", false},
+		{"/mnt/nvme", "/mnt/nvme", false},
+		{"/mnt/nvme/", "/mnt/nvme", false},
+		{"/mnt/other", "", true},
+	}
+	for _, tt := range tests {
+		got, err := d.volumeCacheBase(map[string]string{volumeCacheDirKey: tt.requested})
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("volumeCacheBase(%q) error = %v, wantErr %v", tt.requested, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("volumeCacheBase(%q) = %q, want %q", tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestBuildMountArgsAppliesNodeLimits(t *testing.T) {
+	mounter := &mountServiceMounter{
+		driver: &SeaweedFsDriver{
+			CacheCapacityMB:      100,
+			ConcurrentReaders:    16,
+			MaxCacheCapacityMB:   1024,
+			MaxConcurrentReaders: 32,
+			MaxConcurrentWriters: 64,
+		},
+		volumeID: "/buckets/pvc-1234",
+		volContext: map[string]string{
+			"cacheCapacityMB":   "4096",
+			volumeCacheDirKey:   "/mnt/nvme",
+			"concurrentWriters": "0",
+		},
+	}
+
+	args, err := mounter.buildMountArgs("/staging", "/cache", "/socket", []string{"filer:8888"})
+	if err != nil {
+		t.Fatalf("buildMountArgs: %v", err)
+	}
+	for _, arg := range []string{"-cacheCapacityMB=1024", "-concurrentReaders=16", "-concurrentWriters=64", "-cacheDir=/cache"} {
+		if !slices.Contains(args, arg) {
+			t.Fatalf("mount args do not contain %s: %v", arg, args)
+		}
+	}
+}
+
+func TestCleanupVolumeResourcesRemovesCacheOnEveryRoot(t *testing.T) {
+	d := &SeaweedFsDriver{CacheDir: t.TempDir(), CacheRoots: []string{t.TempDir()}, volumeSocketDir: t.TempDir()}
+	volumeID := "/buckets/pvc-1234"
+
+	var cacheDirs []string
+	for _, root := range d.cacheRoots() {
+		cacheDir := GetCacheDir(root, volumeID)
+		if err := os.MkdirAll(filepath.Join(cacheDir, "chunks"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		cacheDirs = append(cacheDirs, cacheDir)
+	}
+
+	CleanupVolumeResources(d, volumeID)
+
+	for _, cacheDir := range cacheDirs {
+		if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+			t.Fatalf("cache dir %s still exists: %v", cacheDir, err)
+		}
+	}
+}
+
+func TestRemoveStaleCacheDirsKeepsMountedAndForeignDirs(t *testing.T) {
+	roots := []string{t.TempDir(), t.TempDir()}
+	mounted := GetCacheDir(roots[0], "/buckets/pvc-mounted")
+	stale := GetCacheDir(roots[1], "/buckets/pvc-stale")
+	foreign := filepath.Join(roots[0], "other-data")
+	for _, dir := range []string{mounted, stale, foreign} {
+		if err := os.MkdirAll(filepath.Join(dir, "chunks"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removeStaleCacheDirs(append(roots, filepath.Join(t.TempDir(), "missing")), []mountmanager.MountStatus{{CacheDir: mounted}})
+
+	for _, dir := range []string{mounted, foreign} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s was removed: %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale cache dir %s still exists: %v", stale, err)
+	}
+}
@@ -0,0 +1,137 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/seaweedfs/seaweedfs/weed/pb/filer_pb"
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+)
+
+// volumeCollection returns the collection a volume's data is written to,
+// following the same defaulting as CreateVolume and the mount. Volumes in a
+// shared directory write to its collection, which CreateVolume resolves
+// without storing it in the volume's parameters.
+func volumeCollection(volumeId, bucketsDir string, params map[string]string) string {
+	if collection := params["collection"]; collection != "" {
+		return collection
+	}
+	if sharedDir := params[sharedDirParam]; sharedDir != "" {
+		return sharedDirCollection(path.Clean(sharedDir), bucketsDir)
+	}
+	return volumeIdName(volumeId)
+}
+
+// placementCopies returns the number of replicas encoded in a master
+// ReplicaPlacement byte, such as 1 for "000" and 2 for "001".
+func placementCopies(placement uint32) int {
+	return 1 + int(placement/100) + int(placement/10%10) + int(placement%10)
+}
+
+// collectionCondition inspects the SeaweedFS volumes backing a collection.
+// Volume servers that stop heartbeating drop out of the topology, so volumes
+// with fewer replicas than their placement asks for, or gone entirely, are on
+// unreachable servers. hasData tells whether the volume directory holds any
+// entries, without which an absent collection is expected.
+func collectionCondition(topology *master_pb.TopologyInfo, volumeSizeLimitMb uint64, collection, diskType string, hasData bool) *csi.VolumeCondition {
+	type replicaCount struct {
+		found, expected int
+	}
+	replicas := make(map[uint32]*replicaCount)
+	writable := 0
+	sizeLimit := volumeSizeLimitMb * 1024 * 1024
+
+	for _, dc := range topology.GetDataCenterInfos() {
+		for _, rack := range dc.RackInfos {
+			for _, node := range rack.DataNodeInfos {
+				for _, disk := range node.DiskInfos {
+					for _, volume := range disk.VolumeInfos {
+						if volume.Collection != collection {
+							continue
+						}
+						count, ok := replicas[volume.Id]
+						if !ok {
+							count = &replicaCount{expected: placementCopies(volume.ReplicaPlacement)}
+							replicas[volume.Id] = count
+						}
+						count.found++
+						if !volume.ReadOnly && (sizeLimit == 0 || volume.Size < sizeLimit) {
+							writable++
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if len(replicas) == 0 {
+		if hasData {
+			return &csi.VolumeCondition{
+				Abnormal: true,
+				Message:  fmt.Sprintf("collection %s has no volumes on any reachable volume server", collection),
+			}
+		}
+		return &csi.VolumeCondition{Message: fmt.Sprintf("collection %s has no volumes yet", collection)}
+	}
+
+	var degraded []string
+	for id, count := range replicas {
+		if count.found < count.expected {
+			degraded = append(degraded, fmt.Sprintf("%d (%d/%d replicas)", id, count.found, count.expected))
+		}
+	}
+	if len(degraded) > 0 {
+		sort.Strings(degraded)
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("collection %s has volumes with replicas on unreachable volume servers: %s", collection, strings.Join(degraded, ", ")),
+		}
+	}
+
+	if writable == 0 && availableCapacity(topology, volumeSizeLimitMb, capacityFilter{diskType: diskType}) == 0 {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("collection %s is full: all its volumes are full or read-only and no volume slots are free", collection),
+		}
+	}
+
+	return &csi.VolumeCondition{Message: fmt.Sprintf("collection %s has %d volumes, %d writable replicas", collection, len(replicas), writable)}
+}
+
+// volumeCondition reports the health of the data backing the volume at
+// parentDir/volumeName.
+func volumeCondition(ctx context.Context, d *SeaweedFsDriver, bucketsDir, parentDir, volumeName string, params map[string]string) *csi.VolumeCondition {
+	hasData := false
+	err := filer_pb.List(ctx, d, path.Join(parentDir, volumeName), "", func(entry *filer_pb.Entry, isLast bool) error {
+		hasData = true
+		return nil
+	}, "", false, 1)
+	if err != nil {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("cannot list volume directory: %v", err),
+		}
+	}
+
+	var condition *csi.VolumeCondition
+	err = d.WithMasterClient(ctx, func(client master_pb.SeaweedClient) error {
+		resp, err := client.VolumeList(ctx, &master_pb.VolumeListRequest{})
+		if err != nil {
+			return err
+		}
+		collection := volumeCollection(path.Join(parentDir, volumeName), bucketsDir, params)
+		condition = collectionCondition(resp.TopologyInfo, resp.VolumeSizeLimitMb, collection, params["diskType"], hasData)
+		return nil
+	})
+	if err != nil {
+		return &csi.VolumeCondition{
+			Abnormal: true,
+			Message:  fmt.Sprintf("cannot reach SeaweedFS master: %v", err),
+		}
+	}
+	return condition
+}
@@ -0,0 +1,71 @@
+package driver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seaweedfs/seaweedfs/weed/pb/master_pb"
+)
+
+func TestPlacementCopies(t *testing.T) {
+	tests := map[uint32]int{0: 1, 1: 2, 10: 2, 100: 2, 110: 3, 200: 3}
+	for placement, want := range tests {
+		if got := placementCopies(placement); got != want {
+			t.Errorf("placementCopies(%03d) = %d, want %d", placement, got, want)
+		}
+	}
+}
+
+func TestVolumeCollection(t *testing.T) {
+	if got := volumeCollection("/buckets/pvc-1", "/buckets", nil); got != "pvc-1" {
+		t.Errorf("default collection = %q, want pvc-1", got)
+	}
+	if got := volumeCollection("/buckets/pvc-1", "/buckets", map[string]string{"collection": "shared"}); got != "shared" {
+		t.Errorf("explicit collection = %q, want shared", got)
+	}
+	sharedDir := map[string]string{sharedDirParam: "/buckets/team/projects"}
+	if got := volumeCollection("/buckets/team/projects/pvc-1", "/buckets", sharedDir); got != "team" {
+		t.Errorf("shared directory collection = %q, want team", got)
+	}
+	sharedDir["collection"] = "explicit"
+	if got := volumeCollection("/buckets/team/projects/pvc-1", "/buckets", sharedDir); got != "explicit" {
+		t.Errorf("shared directory with explicit collection = %q, want explicit", got)
+	}
+}
+
+func TestCollectionCondition(t *testing.T) {
+	topology := func(free int64, volumes ...*master_pb.VolumeInformationMessage) *master_pb.TopologyInfo {
+		return &master_pb.TopologyInfo{
+			DataCenterInfos: []*master_pb.DataCenterInfo{{Id: "dc1", RackInfos: []*master_pb.RackInfo{{Id: "r1", DataNodeInfos: []*master_pb.DataNodeInfo{{
+				Id: "n1",
+				DiskInfos: map[string]*master_pb.DiskInfo{
+					"": {FreeVolumeCount: free, VolumeInfos: volumes},
+				},
+			}}}}}},
+		}
+	}
+	const mb = 1024 * 1024
+
+	tests := []struct {
+		name     string
+		topology *master_pb.TopologyInfo
+		hasData  bool
+		abnormal bool
+		message  string
+	}{
+		{"empty volume", topology(1), false, false, "no volumes yet"},
+		{"missing collection", topology(1), true, true, "no volumes on any reachable"},
+		{"healthy", topology(1, &master_pb.VolumeInformationMessage{Id: 1, Collection: "c"}), true, false, "1 writable"},
+		{"other collections ignored", topology(1, &master_pb.VolumeInformationMessage{Id: 1, Collection: "other"}), true, true, "no volumes"},
+		{"lost replica", topology(1, &master_pb.VolumeInformationMessage{Id: 1, Collection: "c", ReplicaPlacement: 1}), true, true, "1 (1/2 replicas)"},
+		{"full but can grow", topology(1, &master_pb.VolumeInformationMessage{Id: 1, Collection: "c", Size: 10 * mb}), true, false, "0 writable"},
+		{"full", topology(0, &master_pb.VolumeInformationMessage{Id: 1, Collection: "c", Size: 10 * mb}), true, true, "is full"},
+		{"read-only", topology(0, &master_pb.VolumeInformationMessage{Id: 1, Collection: "c", ReadOnly: true}), true, true, "is full"},
+	}
+	for _, tt := range tests {
+		condition := collectionCondition(tt.topology, 10, "c", "", tt.hasData)
+		if condition.Abnormal != tt.abnormal || !strings.Contains(condition.Message, tt.message) {
+			t.Errorf("%s: got %+v, want abnormal %v with message containing %q", tt.name, condition, tt.abnormal, tt.message)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package driver
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// getVolumeUsage reads the usage of the filesystem mounted at path. The local
+// socket of weed mount cannot report usage, but weed mount answers statfs with
+// the used size the filer reports for the collection and the quota set
+// through that socket, if any.
+func getVolumeUsage(path string) (*volumeUsage, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return nil, err
+	}
+	blockSize := int64(st.Bsize)
+	usage := &volumeUsage{
+		totalBytes:     int64(st.Blocks) * blockSize,
+		availableBytes: int64(st.Bavail) * blockSize,
+		usedBytes:      int64(st.Blocks-st.Bfree) * blockSize,
+		totalInodes:    int64(st.Files),
+		freeInodes:     int64(st.Ffree),
+		usedInodes:     int64(st.Files - st.Ffree),
+	}
+	return usage, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package driver
+
+import (
+	"errors"
+)
+
+// getVolumeUsage is not supported on non-Linux platforms.
+func getVolumeUsage(path string) (*volumeUsage, error) {
+	return nil, errors.New("volume usage is only available on Linux")
+}
@@ -0,0 +1,182 @@
+package mountmanager
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+)
+
+const (
+	cgroupMountPoint = "/sys/fs/cgroup"
+	// cgroupControllers are enabled for the sub-groups of weed mount
+	// processes
+	cgroupControllers = "+memory +cpu"
+	// cgroupCPUPeriod is the cpu.max period, in microseconds
+	cgroupCPUPeriod = 100000
+)
+
+// setupCgroups prepares the cgroup v2 hierarchy the weed mount processes are
+// placed in and returns its directory. The processes of the manager's own
+// cgroup move to a "manager" leaf first: cgroup v2 only enables controllers
+// for the children of groups without processes of their own.
+func setupCgroups() (string, error) {
+	data, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	own, err := unifiedCgroupPath(data)
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Join(cgroupMountPoint, own)
+	if filepath.Base(root) == "manager" {
+		// set up by a previous manager in this cgroup
+		root = filepath.Dir(root)
+	}
+
+	controllers, err := os.ReadFile(filepath.Join(root, "cgroup.controllers"))
+	if err != nil {
+		return "", fmt.Errorf("cgroup v2 is not available at %s: %w", root, err)
+	}
+	for _, controller := range []string{"memory", "cpu"} {
+		if !strings.Contains(" "+strings.TrimSpace(string(controllers))+" ", " "+controller+" ") {
+			return "", fmt.Errorf("the %s controller is not available in %s", controller, root)
+		}
+	}
+
+	leaf := filepath.Join(root, "manager")
+	if err := os.MkdirAll(leaf, 0755); err != nil {
+		return "", err
+	}
+	procs, err := os.ReadFile(filepath.Join(root, "cgroup.procs"))
+	if err != nil {
+		return "", err
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(pid), 0644); err != nil {
+			return "", fmt.Errorf("moving process %s to %s: %w", pid, leaf, err)
+		}
+	}
+
+	mounts := filepath.Join(root, "mounts")
+	for _, dir := range []string{root, mounts} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte(cgroupControllers), 0644); err != nil {
+			return "", fmt.Errorf("enabling controllers in %s: %w", dir, err)
+		}
+	}
+	return mounts, nil
+}
+
+// unifiedCgroupPath returns the cgroup v2 path in the contents of
+// /proc/<pid>/cgroup.
+func unifiedCgroupPath(data []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if path, ok := strings.CutPrefix(scanner.Text(), "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", errors.New("not in a cgroup v2 hierarchy")
+}
+
+// mountCgroupName returns the name of the sub-group of the mount of
+// volumeID: volume IDs are filer paths, so a readable part of it plus a hash
+// keeps it a single, unique path element.
+func mountCgroupName(volumeID string) string {
+	sum := sha256.Sum256([]byte(volumeID))
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		}
+		return '_'
+	}, strings.TrimPrefix(volumeID, "/"))
+	if len(name) > 64 {
+		name = name[len(name)-64:]
+	}
+	return fmt.Sprintf("%s-%x", name, sum[:4])
+}
+
+// mountCgroup returns the cgroup to start the weed mount process of
+// volumeID in, with the given limits. It is empty when the manager does not
+// use cgroups.
+func (m *Manager) mountCgroup(volumeID string, memoryLimitMB, cpuLimitMillicores int64) (string, error) {
+	if m.cgroupRoot == "" {
+		if memoryLimitMB > 0 || cpuLimitMillicores > 0 {
+			glog.Warningf("volume %s: ignoring the resource limits of the mount, cgroups are disabled", volumeID)
+		}
+		return "", nil
+	}
+	dir, err := createMountCgroup(m.cgroupRoot, volumeID, memoryLimitMB, cpuLimitMillicores)
+	if err != nil {
+		return "", fmt.Errorf("creating cgroup: %w", err)
+	}
+	return dir, nil
+}
+
+// createMountCgroup creates the sub-group of the mount of volumeID below
+// cgroupRoot with the given limits, 0 meaning unlimited. A group left over
+// from a previous process of the mount is replaced, so that its events
+// only count the new process.
+func createMountCgroup(cgroupRoot, volumeID string, memoryLimitMB, cpuLimitMillicores int64) (string, error) {
+	dir := filepath.Join(cgroupRoot, mountCgroupName(volumeID))
+	if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("removing previous cgroup %s: %w", dir, err)
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", err
+	}
+	memoryMax := "max"
+	if memoryLimitMB > 0 {
+		memoryMax = strconv.FormatInt(memoryLimitMB*1024*1024, 10)
+	}
+	cpuMax := "max"
+	if cpuLimitMillicores > 0 {
+		cpuMax = strconv.FormatInt(cpuLimitMillicores*cgroupCPUPeriod/1000, 10)
+	}
+	limits := map[string]string{
+		"memory.max": memoryMax,
+		"cpu.max":    fmt.Sprintf("%s %d", cpuMax, cgroupCPUPeriod),
+	}
+	for file, value := range limits {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			_ = os.Remove(dir)
+			return "", fmt.Errorf("setting %s: %w", file, err)
+		}
+	}
+	return dir, nil
+}
+
+// removeMountCgroup removes the cgroup of a weed mount process that exited.
+func removeMountCgroup(dir string) {
+	if err := os.Remove(dir); err != nil && !os.IsNotExist(err) {
+		glog.Warningf("failed to remove cgroup %s: %v", dir, err)
+	}
+}
+
+// cgroupOOMKills returns how many processes of the cgroup the kernel
+// OOM-killed, from its memory.events.
+func cgroupOOMKills(dir string) int {
+	data, err := os.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if value, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			kills, _ := strconv.Atoi(strings.TrimSpace(value))
+			return kills
+		}
+	}
+	return 0
+}
@@ -0,0 +1,19 @@
+package mountmanager
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// startInCgroup makes cmd start directly in the cgroup directory dir, so
+// that not even its first allocations escape the limits. The caller closes
+// dir once cmd has started.
+func startInCgroup(cmd *exec.Cmd, dir *os.File) error {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package mountmanager
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func startInCgroup(cmd *exec.Cmd, dir *os.File) error {
+	return errors.New("cgroups are only supported on Linux")
+}
@@ -0,0 +1,104 @@
+package mountmanager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedCgroupPath(t *testing.T) {
+	data := []byte("12:memory:/legacy\n0::/kubepods/pod1/mount\n")
+	if path, err := unifiedCgroupPath(data); err != nil || path != "/kubepods/pod1/mount" {
+		t.Errorf("unifiedCgroupPath = %q, %v, want /kubepods/pod1/mount", path, err)
+	}
+	if _, err := unifiedCgroupPath([]byte("12:memory:/legacy\n")); err == nil {
+		t.Error("expected an error without a cgroup v2 entry")
+	}
+}
+
+func TestMountCgroupName(t *testing.T) {
+	a := mountCgroupName("/buckets/data")
+	b := mountCgroupName("/buckets_data")
+	if a == b {
+		t.Errorf("volumes /buckets/data and /buckets_data share cgroup %q", a)
+	}
+	if strings.Contains(a, "/") || !strings.HasPrefix(a, "buckets_data-") {
+		t.Errorf("unexpected cgroup name %q", a)
+	}
+	if name := mountCgroupName("/" + strings.Repeat("x", 300)); len(name) > 80 {
+		t.Errorf("cgroup name of a long volume ID is %d bytes long", len(name))
+	}
+}
+
+func TestCreateMountCgroupLimits(t *testing.T) {
+	root := t.TempDir()
+
+	dir, err := createMountCgroup(root, "/buckets/data", 256, 1500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{
+		"memory.max": "268435456",
+		"cpu.max":    "150000 100000",
+	} {
+		if data, err := os.ReadFile(filepath.Join(dir, file)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", file, data, err, want)
+		}
+	}
+
+	// the interface files of a real cgroup do not keep it from being
+	// removed, those of the test directory do
+	for _, file := range []string{"memory.max", "cpu.max"} {
+		_ = os.Remove(filepath.Join(dir, file))
+	}
+	dir, err = createMountCgroup(root, "/buckets/data", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{
+		"memory.max": "max",
+		"cpu.max":    "max 100000",
+	} {
+		if data, err := os.ReadFile(filepath.Join(dir, file)); err != nil || string(data) != want {
+			t.Errorf("unlimited %s = %q, %v, want %q", file, data, err, want)
+		}
+	}
+}
+
+func TestCgroupOOMKills(t *testing.T) {
+	dir := t.TempDir()
+	if kills := cgroupOOMKills(dir); kills != 0 {
+		t.Errorf("without memory.events: %d OOM kills", kills)
+	}
+	events := "low 0\nhigh 0\nmax 12\noom 2\noom_kill 1\noom_group_kill 0\n"
+	if err := os.WriteFile(filepath.Join(dir, "memory.events"), []byte(events), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if kills := cgroupOOMKills(dir); kills != 1 {
+		t.Errorf("got %d OOM kills, want 1", kills)
+	}
+}
+
+func TestMountCgroupWithoutCgroups(t *testing.T) {
+	m := NewManager(Config{})
+	if dir, err := m.mountCgroup("/buckets/data", 256, 500); dir != "" || err != nil {
+		t.Errorf("mountCgroup without cgroups = %q, %v", dir, err)
+	}
+}
+
+func TestStartWeedMountProcessRemovesCgroupOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	cgroupDir := filepath.Join(dir, "mount")
+	if err := os.Mkdir(cgroupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	logPath := filepath.Join(dir, "missing", "weed-mount.log")
+	if _, err := startWeedMountProcess("true", nil, filepath.Join(dir, "target"), "vol-1", logPath, cgroupDir); err == nil {
+		t.Fatal("expected an error without a log directory")
+	}
+	if _, err := os.Stat(cgroupDir); !os.IsNotExist(err) {
+		t.Fatalf("cgroup %s was left behind: %v", cgroupDir, err)
+	}
+}
@@ -9,13 +9,38 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"slices"
+	"sync"
 	"time"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager/mountv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
-// Client talks to the mount service over a Unix domain socket.
+// clientTimeout bounds the requests of a Client, except for event streams.
+const clientTimeout = 30 * time.Second
+
+// ErrEventsUnsupported is returned by WatchEvents for mount services that
+// only serve the JSON API.
+var ErrEventsUnsupported = errors.New("the mount service does not stream events")
+
+// Client talks to the mount service over a Unix domain socket. It uses the
+// gRPC API if the mount service serves it, and the JSON API otherwise.
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
+	address    string
+
+	mu sync.Mutex
+	// negotiated is set once the API of the mount service is known; api
+	// is nil for mount services without the gRPC API
+	negotiated bool
+	api        mountv1.MountServiceClient
+	conn       *grpc.ClientConn
 }
 
 // NewClient builds a new Client for the given endpoint.
@@ -37,15 +62,73 @@ func NewClient(endpoint string) (*Client, error) {
 
 	return &Client{
 		httpClient: &http.Client{
-			Timeout:   30 * time.Second,
+			Timeout:   clientTimeout,
 			Transport: transport,
 		},
 		baseURL: "http://unix",
+		address: address,
 	}, nil
 }
 
+// Close releases the connection of the gRPC API. The Client stays usable:
+// it negotiates the API and connects again on its next request.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	if c.conn != nil {
+		err = c.conn.Close()
+	}
+	c.conn, c.api, c.negotiated = nil, nil, false
+	return err
+}
+
+// grpcAPI returns the gRPC client of the mount service, or nil if it only
+// serves the JSON API. The API is negotiated on first use; a mount service
+// that cannot be reached is asked again next time.
+func (c *Client) grpcAPI() (mountv1.MountServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.negotiated {
+		return c.api, nil
+	}
+
+	var versions APIVersionsResponse
+	err := c.do(http.MethodGet, APIVersionsPath, nil, &versions)
+	var httpErr *httpStatusError
+	switch {
+	case errors.As(err, &httpErr) && httpErr.code == http.StatusNotFound:
+		// predates the gRPC API
+	case err != nil:
+		return nil, err
+	case slices.Contains(versions.GRPCServices, GRPCServiceName):
+		conn, err := grpc.NewClient("unix:"+c.address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, fmt.Errorf("connect to mount service: %w", err)
+		}
+		c.conn = conn
+		c.api = mountv1.NewMountServiceClient(conn)
+	}
+	c.negotiated = true
+	return c.api, nil
+}
+
 // Mount mounts a volume using the mount service.
 func (c *Client) Mount(req *MountRequest) (*MountResponse, error) {
+	api, err := c.grpcAPI()
+	if err != nil {
+		return nil, err
+	}
+	if api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), clientTimeout)
+		defer cancel()
+		resp, err := api.Mount(ctx, mountRequestToProto(req))
+		if err != nil {
+			return nil, clientError(err)
+		}
+		return &MountResponse{LocalSocket: resp.GetLocalSocket()}, nil
+	}
+
 	var resp MountResponse
 	if err := c.doPost("/mount", req, &resp); err != nil {
 		return nil, err
@@ -55,6 +138,19 @@ func (c *Client) Mount(req *MountRequest) (*MountResponse, error) {
 
 // Unmount unmounts a volume using the mount service.
 func (c *Client) Unmount(req *UnmountRequest) (*UnmountResponse, error) {
+	api, err := c.grpcAPI()
+	if err != nil {
+		return nil, err
+	}
+	if api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), clientTimeout)
+		defer cancel()
+		if _, err := api.Unmount(ctx, &mountv1.UnmountRequest{VolumeId: req.VolumeID}); err != nil {
+			return nil, clientError(err)
+		}
+		return &UnmountResponse{}, nil
+	}
+
 	var resp UnmountResponse
 	if err := c.doPost("/unmount", req, &resp); err != nil {
 		return nil, err
@@ -62,17 +158,139 @@ func (c *Client) Unmount(req *UnmountRequest) (*UnmountResponse, error) {
 	return &resp, nil
 }
 
+// List returns the mounts of the mount service.
+func (c *Client) List() ([]MountStatus, error) {
+	api, err := c.grpcAPI()
+	if err != nil {
+		return nil, err
+	}
+	if api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), clientTimeout)
+		defer cancel()
+		resp, err := api.List(ctx, &mountv1.ListRequest{})
+		if err != nil {
+			return nil, clientError(err)
+		}
+		mounts := make([]MountStatus, 0, len(resp.GetMounts()))
+		for _, msg := range resp.GetMounts() {
+			mounts = append(mounts, *mountStatusFromProto(msg))
+		}
+		return mounts, nil
+	}
+
+	var resp ListMountsResponse
+	if err := c.do(http.MethodGet, "/mounts", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Mounts, nil
+}
+
+// Status returns the mount of a volume, or ErrVolumeNotMounted.
+func (c *Client) Status(volumeID string) (*MountStatus, error) {
+	api, err := c.grpcAPI()
+	if err != nil {
+		return nil, err
+	}
+	if api != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), clientTimeout)
+		defer cancel()
+		msg, err := api.Status(ctx, &mountv1.StatusRequest{VolumeId: volumeID})
+		if err != nil {
+			return nil, clientError(err)
+		}
+		return mountStatusFromProto(msg), nil
+	}
+
+	var resp MountStatus
+	if err := c.do(http.MethodGet, "/status?volumeId="+url.QueryEscape(volumeID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// EventStream receives the events of a WatchEvents call.
+type EventStream struct {
+	stream mountv1.MountService_WatchEventsClient
+}
+
+// Recv returns the next event. It fails once the context of the stream is
+// done, the mount service goes away, or the stream fell too far behind.
+func (s *EventStream) Recv() (*MountEvent, error) {
+	msg, err := s.stream.Recv()
+	if err != nil {
+		return nil, clientError(err)
+	}
+	return mountEventFromProto(msg), nil
+}
+
+// WatchEvents streams the events of the mounts of volumeID, or of all mounts
+// if volumeID is empty, until ctx is done. It returns once the mount service
+// has subscribed the stream, or ErrEventsUnsupported if it only serves the
+// JSON API.
+func (c *Client) WatchEvents(ctx context.Context, volumeID string) (*EventStream, error) {
+	api, err := c.grpcAPI()
+	if err != nil {
+		return nil, err
+	}
+	if api == nil {
+		// ask again next time, the mount service may have been upgraded
+		c.mu.Lock()
+		c.negotiated = false
+		c.mu.Unlock()
+		return nil, ErrEventsUnsupported
+	}
+	stream, err := api.WatchEvents(ctx, &mountv1.WatchEventsRequest{VolumeId: volumeID})
+	if err != nil {
+		return nil, clientError(err)
+	}
+	if _, err := stream.Header(); err != nil {
+		return nil, clientError(err)
+	}
+	return &EventStream{stream: stream}, nil
+}
+
+// clientError returns the error of a gRPC status, turning NotFound back into
+// ErrVolumeNotMounted.
+func clientError(err error) error {
+	if status.Code(err) == codes.NotFound {
+		return ErrVolumeNotMounted
+	}
+	return err
+}
+
+// httpStatusError is returned for JSON API responses with an error status
+// that carry no error message.
+type httpStatusError struct {
+	code   int
+	status string
+	body   string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("mount service error: %s (%s)", e.status, e.body)
+}
+
 func (c *Client) doPost(path string, payload any, out any) error {
-	body := &bytes.Buffer{}
-	if err := json.NewEncoder(body).Encode(payload); err != nil {
-		return fmt.Errorf("encode request: %w", err)
+	return c.do(http.MethodPost, path, payload, out)
+}
+
+func (c *Client) do(method, path string, payload any, out any) error {
+	var body io.Reader
+	if payload != nil {
+		buf := &bytes.Buffer{}
+		if err := json.NewEncoder(buf).Encode(payload); err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		body = buf
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.baseURL+path, body)
+	req, err := http.NewRequest(method, c.baseURL+path, body)
 	if err != nil {
 		return fmt.Errorf("build request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -83,13 +301,16 @@ func (c *Client) doPost(path string, payload any, out any) error {
 	if resp.StatusCode >= 400 {
 		var errResp ErrorResponse
 		if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && errResp.Error != "" {
+			if resp.StatusCode == http.StatusNotFound && errResp.Error == ErrVolumeNotMounted.Error() {
+				return ErrVolumeNotMounted
+			}
 			return errors.New(errResp.Error)
 		}
 		data, readErr := io.ReadAll(resp.Body)
 		if readErr != nil {
 			return fmt.Errorf("mount service error: %s (failed to read body: %v)", resp.Status, readErr)
 		}
-		return fmt.Errorf("mount service error: %s (%s)", resp.Status, string(data))
+		return &httpStatusError{code: resp.StatusCode, status: resp.Status, body: string(data)}
 	}
 
 	if out == nil {
@@ -0,0 +1,111 @@
+package mountmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serveMountService serves server on a unix socket and returns a client
+// talking to it.
+func serveMountService(t *testing.T, server *http.Server) *Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "mount.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("cannot listen on unix socket: %v", err)
+	}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	client, err := NewClient("unix://" + socket)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestClientStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mounts", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ListMountsResponse{Mounts: []MountStatus{{VolumeID: "vol-1", Pid: 42}}})
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("volumeId") != "vol-1" {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(ErrorResponse{Error: ErrVolumeNotMounted.Error()})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(MountStatus{VolumeID: "vol-1", Pid: 42})
+	})
+	// a mount service predating the gRPC API
+	client := serveMountService(t, &http.Server{Handler: mux})
+
+	mounts, err := client.List()
+	if err != nil || len(mounts) != 1 || mounts[0].Pid != 42 {
+		t.Fatalf("List() = %+v, %v", mounts, err)
+	}
+	status, err := client.Status("vol-1")
+	if err != nil || status.VolumeID != "vol-1" {
+		t.Fatalf("Status(vol-1) = %+v, %v", status, err)
+	}
+	if _, err := client.Status("vol-2"); err != ErrVolumeNotMounted {
+		t.Fatalf("Status(vol-2) error = %v, want ErrVolumeNotMounted", err)
+	}
+	if _, err := client.WatchEvents(context.Background(), ""); err != ErrEventsUnsupported {
+		t.Fatalf("WatchEvents error = %v, want ErrEventsUnsupported", err)
+	}
+	if client.negotiated {
+		t.Fatal("JSON-only API still negotiated after WatchEvents, an upgraded mount service would never be asked again")
+	}
+}
+
+func TestClientGRPC(t *testing.T) {
+	m := NewManager(Config{})
+	m.mounts["vol-1"] = &mountEntry{
+		volumeID:   "vol-1",
+		targetPath: "/a",
+		args:       []string{"mount"},
+		startTime:  time.Now(),
+		process:    &weedMountProcess{exited: make(chan struct{}), done: make(chan struct{})},
+	}
+	client := serveMountService(t, NewServer(m, http.NewServeMux()))
+
+	if api, err := client.grpcAPI(); err != nil || api == nil {
+		t.Fatalf("grpcAPI() = %v, %v, want the gRPC API", api, err)
+	}
+	mounts, err := client.List()
+	if err != nil || len(mounts) != 1 || mounts[0].TargetPath != "/a" || mounts[0].StartTime.IsZero() {
+		t.Fatalf("List() = %+v, %v", mounts, err)
+	}
+	if _, err := client.Status("vol-2"); err != ErrVolumeNotMounted {
+		t.Fatalf("Status(vol-2) error = %v, want ErrVolumeNotMounted", err)
+	}
+	if _, err := client.Mount(&MountRequest{VolumeID: "vol-2"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Mount without target error = %v, want InvalidArgument", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.WatchEvents(ctx, "vol-1")
+	if err != nil {
+		t.Fatalf("WatchEvents: %v", err)
+	}
+	m.publish(MountEvent{Type: EventExited, VolumeID: "vol-2"})
+	m.publish(MountEvent{Type: EventRestarted, VolumeID: "vol-1", Pid: 42, RestartCount: 1})
+	event, err := events.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if event.Type != EventRestarted || event.VolumeID != "vol-1" || event.Pid != 42 || event.RestartCount != 1 {
+		t.Errorf("event = %+v, want the restart of vol-1", event)
+	}
+}
@@ -0,0 +1,119 @@
+package mountmanager
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// credentialsDirName is the directory below the cache directory of a mount
+// its credentials are written to, so that they are removed with the cache.
+const credentialsDirName = ".credentials"
+
+// nodeConfigDirs are the directories weed looks for the security.toml of the
+// node in, in its order.
+var nodeConfigDirs = []string{".", "$HOME/.seaweedfs", "/usr/local/etc/seaweedfs", "/etc/seaweedfs"}
+
+// readNodeSecurityToml returns the security.toml weed would use without
+// -config_dir, or nil if there is none.
+func readNodeSecurityToml() ([]byte, error) {
+	for _, dir := range nodeConfigDirs {
+		content, err := os.ReadFile(filepath.Join(os.ExpandEnv(dir), "security.toml"))
+		if err == nil {
+			return content, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// writeCredentials writes the security.toml of the node with creds applied,
+// and the certificate files it refers to, into a directory only the mount
+// service can read. It returns the directory, to be passed to weed as
+// -config_dir.
+func writeCredentials(cacheDir string, creds *MountCredentials) (string, error) {
+	dir := filepath.Join(cacheDir, credentialsDirName)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	// MkdirAll keeps the mode of an existing directory
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", err
+	}
+
+	nodeConfig, err := readNodeSecurityToml()
+	if err != nil {
+		return "", fmt.Errorf("reading the node's security.toml: %w", err)
+	}
+	config, err := securityToml(dir, creds, nodeConfig)
+	if err != nil {
+		return "", err
+	}
+
+	files := map[string]string{
+		"tls.crt":       creds.TLSCert,
+		"tls.key":       creds.TLSKey,
+		"ca.crt":        creds.CACert,
+		"security.toml": config,
+	}
+	for name, content := range files {
+		if content == "" {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			return "", fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	return dir, nil
+}
+
+// securityToml renders nodeConfig, the security.toml of the node, with the
+// settings of creds replacing its own, and the certificate files in dir.
+// weed reads a single security.toml, so the settings creds do not cover,
+// such as HTTPS or the signing keys of other servers, are kept.
+func securityToml(dir string, creds *MountCredentials, nodeConfig []byte) (string, error) {
+	config := map[string]any{}
+	if err := toml.Unmarshal(nodeConfig, &config); err != nil {
+		return "", fmt.Errorf("parsing the node's security.toml: %w", err)
+	}
+	set := func(key, value string) {
+		table := config
+		names := strings.Split(key, ".")
+		for _, name := range names[:len(names)-1] {
+			child, ok := table[name].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				table[name] = child
+			}
+			table = child
+		}
+		table[names[len(names)-1]] = value
+	}
+
+	if creds.FilerSigningKey != "" {
+		set("jwt.filer_signing.key", creds.FilerSigningKey)
+	}
+	if creds.FilerReadSigningKey != "" {
+		set("jwt.filer_signing.read.key", creds.FilerReadSigningKey)
+	}
+	if creds.CACert != "" {
+		set("grpc.ca", filepath.Join(dir, "ca.crt"))
+	}
+	if creds.TLSCert != "" {
+		set("grpc.client.cert", filepath.Join(dir, "tls.crt"))
+		set("grpc.client.key", filepath.Join(dir, "tls.key"))
+	}
+
+	content, err := toml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,125 @@
+package mountmanager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+func TestWriteCredentials(t *testing.T) {
+	cacheDir := t.TempDir()
+	creds := &MountCredentials{
+		TLSCert:         "CERT",
+		TLSKey:          "KEY",
+		CACert:          "CA",
+		FilerSigningKey: `se"cret`,
+	}
+
+	dir, err := writeCredentials(cacheDir, creds)
+	if err != nil {
+		t.Fatalf("writeCredentials: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Fatalf("credentials dir mode = %v, want 0700", info.Mode().Perm())
+	}
+
+	key, err := os.ReadFile(filepath.Join(dir, "tls.key"))
+	if err != nil || string(key) != "KEY" {
+		t.Fatalf("tls.key = %q, %v", key, err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "security.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config map[string]any
+	if err := toml.Unmarshal(content, &config); err != nil {
+		t.Fatalf("security.toml: %v\n%s", err, content)
+	}
+	for key, want := range map[string]any{
+		"jwt.filer_signing.key": `se"cret`,
+		"grpc.ca":               filepath.Join(dir, "ca.crt"),
+		"grpc.client.cert":      filepath.Join(dir, "tls.crt"),
+		"grpc.client.key":       filepath.Join(dir, "tls.key"),
+	} {
+		if got := tomlValue(config, key); got != want {
+			t.Fatalf("%s = %v, want %v", key, got, want)
+		}
+	}
+	if got := tomlValue(config, "jwt.filer_signing.read"); got != nil {
+		t.Fatalf("security.toml has a read key section without read key:\n%s", content)
+	}
+}
+
+// tomlValue returns the value of the dotted key in config, or nil.
+func tomlValue(config map[string]any, key string) any {
+	var value any = config
+	for _, name := range strings.Split(key, ".") {
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = table[name]
+	}
+	return value
+}
+
+func TestWriteCredentialsKeepsNodeSettings(t *testing.T) {
+	nodeDir := t.TempDir()
+	nodeConfig := `[grpc]
+ca = "/etc/seaweedfs/ca.crt"
+
+[grpc.client]
+cert = "/etc/seaweedfs/client.crt"
+key = "/etc/seaweedfs/client.key"
+
+[jwt.filer_signing]
+key = "node"
+expires_after_seconds = 30
+
+[https.client]
+enabled = true
+`
+	if err := os.WriteFile(filepath.Join(nodeDir, "security.toml"), []byte(nodeConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defaultDirs := nodeConfigDirs
+	t.Cleanup(func() { nodeConfigDirs = defaultDirs })
+	nodeConfigDirs = []string{filepath.Join(nodeDir, "missing"), nodeDir}
+
+	dir, err := writeCredentials(t.TempDir(), &MountCredentials{FilerSigningKey: "tenant"})
+	if err != nil {
+		t.Fatalf("writeCredentials: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "security.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config map[string]any
+	if err := toml.Unmarshal(content, &config); err != nil {
+		t.Fatalf("security.toml: %v\n%s", err, content)
+	}
+	for key, want := range map[string]any{
+		"jwt.filer_signing.key":                   "tenant",
+		"jwt.filer_signing.expires_after_seconds": int64(30),
+		"grpc.ca":              "/etc/seaweedfs/ca.crt",
+		"grpc.client.cert":     "/etc/seaweedfs/client.crt",
+		"grpc.client.key":      "/etc/seaweedfs/client.key",
+		"https.client.enabled": true,
+	} {
+		if got := tomlValue(config, key); got != want {
+			t.Fatalf("%s = %v, want %v\n%s", key, got, want, content)
+		}
+	}
+
+	nodeConfigDirs = []string{nodeDir + "/missing"}
+	if _, err := writeCredentials(t.TempDir(), &MountCredentials{FilerSigningKey: "tenant"}); err != nil {
+		t.Fatalf("writeCredentials without a node security.toml: %v", err)
+	}
+}
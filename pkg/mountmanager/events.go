@@ -0,0 +1,89 @@
+package mountmanager
+
+import (
+	"sync"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+)
+
+// eventBufferSize is how many events a subscriber may fall behind before
+// it is dropped.
+const eventBufferSize = 64
+
+// eventHub fans the events of the manager out to its subscribers.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[*eventSubscriber]struct{}
+}
+
+type eventSubscriber struct {
+	// volumeID filters the events, all are sent when empty
+	volumeID string
+	events   chan MountEvent
+}
+
+// Subscribe returns a channel receiving the events of the mounts of volumeID,
+// or of all mounts if volumeID is empty, and a function to cancel the
+// subscription. The channel is closed when the subscription is cancelled or
+// when the subscriber falls too far behind; it then has to subscribe again
+// and catch up through List.
+func (m *Manager) Subscribe(volumeID string) (<-chan MountEvent, func()) {
+	sub := &eventSubscriber{
+		volumeID: volumeID,
+		events:   make(chan MountEvent, eventBufferSize),
+	}
+	h := &m.events
+	h.mu.Lock()
+	if h.subscribers == nil {
+		h.subscribers = make(map[*eventSubscriber]struct{})
+	}
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub.events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.removeLocked(sub)
+	}
+}
+
+// publish sends event to the subscribers without blocking on them.
+func (m *Manager) publish(event MountEvent) {
+	h := &m.events
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.volumeID != "" && sub.volumeID != event.VolumeID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			glog.Warningf("dropping a mount event subscriber that fell %d events behind", eventBufferSize)
+			h.removeLocked(sub)
+		}
+	}
+}
+
+func (h *eventHub) removeLocked(sub *eventSubscriber) {
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// event returns an event of type about the process of e.
+func (e *mountEntry) event(eventType EventType, now time.Time) MountEvent {
+	event := MountEvent{
+		Type:         eventType,
+		VolumeID:     e.volumeID,
+		TargetPath:   e.targetPath,
+		Time:         now,
+		RestartCount: e.restartCount,
+	}
+	if e.process.process != nil {
+		event.Pid = e.process.process.Pid
+	}
+	return event
+}
@@ -0,0 +1,73 @@
+package mountmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeFiltersByVolume(t *testing.T) {
+	m := NewManager(Config{})
+	all, cancelAll := m.Subscribe("")
+	defer cancelAll()
+	one, cancelOne := m.Subscribe("vol-1")
+	defer cancelOne()
+
+	m.publish(MountEvent{Type: EventStarted, VolumeID: "vol-2"})
+	m.publish(MountEvent{Type: EventStarted, VolumeID: "vol-1"})
+
+	if event := <-all; event.VolumeID != "vol-2" {
+		t.Errorf("first event of all volumes is of %s, want vol-2", event.VolumeID)
+	}
+	if event := <-all; event.VolumeID != "vol-1" {
+		t.Errorf("second event of all volumes is of %s, want vol-1", event.VolumeID)
+	}
+	if event := <-one; event.VolumeID != "vol-1" || len(one) != 0 {
+		t.Errorf("vol-1 subscriber got %+v and %d more", event, len(one))
+	}
+
+	cancelOne()
+	if _, ok := <-one; ok {
+		t.Error("cancelled subscription is still open")
+	}
+	cancelOne()
+}
+
+func TestSubscriberFallingBehindIsDropped(t *testing.T) {
+	m := NewManager(Config{})
+	events, cancel := m.Subscribe("")
+	defer cancel()
+
+	for i := 0; i <= eventBufferSize; i++ {
+		m.publish(MountEvent{Type: EventStarted, VolumeID: "vol-1"})
+	}
+	received := 0
+	for range events {
+		received++
+	}
+	if received != eventBufferSize {
+		t.Errorf("received %d events before the drop, want %d", received, eventBufferSize)
+	}
+}
+
+func TestWatchProcessExitPublishesExit(t *testing.T) {
+	m := NewManager(Config{})
+	events, cancel := m.Subscribe("vol-1")
+	defer cancel()
+
+	process := &weedMountProcess{exited: make(chan struct{}), done: make(chan struct{})}
+	process.stopping.Store(true)
+	entry := &mountEntry{volumeID: "vol-1", targetPath: "/a", process: process}
+	m.mounts["vol-1"] = entry
+	close(process.exited)
+	close(process.done)
+	m.watchProcessExit("vol-1", entry)
+
+	select {
+	case event := <-events:
+		if event.Type != EventExited || !event.Stopped || event.Restarting || event.TargetPath != "/a" {
+			t.Errorf("event = %+v, want the requested exit of the process at /a", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no exit event")
+	}
+}
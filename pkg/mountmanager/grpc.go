@@ -0,0 +1,263 @@
+package mountmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager/mountv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServiceName is the gRPC service the mount service announces through
+// APIVersionsPath when it serves the gRPC API.
+const GRPCServiceName = "seaweedfs.mount.v1.MountService"
+
+// APIVersionsPath is the JSON endpoint clients negotiate the API through.
+// Mount services predating the gRPC API answer it with 404.
+const APIVersionsPath = "/api-versions"
+
+// APIVersionsResponse lists the APIs served on the socket besides JSON.
+type APIVersionsResponse struct {
+	GRPCServices []string `json:"grpcServices"`
+}
+
+// NewServer returns the HTTP server of the mount service: it serves the
+// gRPC API of manager over HTTP/2 without TLS, and the JSON API of jsonAPI
+// to everything else, so both share the socket.
+func NewServer(manager *Manager, jsonAPI *http.ServeMux) *http.Server {
+	grpcServer := grpc.NewServer()
+	mountv1.RegisterMountServiceServer(grpcServer, &grpcService{manager: manager})
+
+	jsonAPI.HandleFunc(APIVersionsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(APIVersionsResponse{GRPCServices: []string{GRPCServiceName}})
+	})
+
+	protocols := &http.Protocols{}
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server := &http.Server{
+		Protocols: protocols,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+				grpcServer.ServeHTTP(w, r)
+				return
+			}
+			jsonAPI.ServeHTTP(w, r)
+		}),
+	}
+	// ends the event streams, which would otherwise hold up the shutdown
+	server.RegisterOnShutdown(grpcServer.Stop)
+	return server
+}
+
+// grpcService implements the gRPC API on top of a Manager.
+type grpcService struct {
+	mountv1.UnimplementedMountServiceServer
+	manager *Manager
+}
+
+func (s *grpcService) Mount(_ context.Context, req *mountv1.MountRequest) (*mountv1.MountResponse, error) {
+	mountReq := mountRequestFromProto(req)
+	if err := validateMountRequest(mountReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	resp, err := s.manager.Mount(mountReq)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &mountv1.MountResponse{LocalSocket: resp.LocalSocket}, nil
+}
+
+func (s *grpcService) Unmount(_ context.Context, req *mountv1.UnmountRequest) (*mountv1.UnmountResponse, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volumeId is required")
+	}
+	if _, err := s.manager.Unmount(&UnmountRequest{VolumeID: req.GetVolumeId()}); err != nil {
+		return nil, grpcError(err)
+	}
+	return &mountv1.UnmountResponse{}, nil
+}
+
+func (s *grpcService) List(context.Context, *mountv1.ListRequest) (*mountv1.ListResponse, error) {
+	mounts := s.manager.List()
+	resp := &mountv1.ListResponse{Mounts: make([]*mountv1.MountStatus, 0, len(mounts))}
+	for i := range mounts {
+		resp.Mounts = append(resp.Mounts, mountStatusToProto(&mounts[i]))
+	}
+	return resp, nil
+}
+
+func (s *grpcService) Status(_ context.Context, req *mountv1.StatusRequest) (*mountv1.MountStatus, error) {
+	if req.GetVolumeId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "volumeId is required")
+	}
+	mountStatus, err := s.manager.Status(req.GetVolumeId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return mountStatusToProto(mountStatus), nil
+}
+
+func (s *grpcService) WatchEvents(req *mountv1.WatchEventsRequest, stream mountv1.MountService_WatchEventsServer) error {
+	events, cancel := s.manager.Subscribe(req.GetVolumeId())
+	defer cancel()
+	// the headers tell the client that it is subscribed
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "fell behind the mount events")
+			}
+			if err := stream.Send(mountEventToProto(&event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// grpcError returns the status of a Manager error.
+func grpcError(err error) error {
+	if errors.Is(err, ErrVolumeNotMounted) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+func mountRequestToProto(req *MountRequest) *mountv1.MountRequest {
+	msg := &mountv1.MountRequest{
+		VolumeId:           req.VolumeID,
+		TargetPath:         req.TargetPath,
+		CacheDir:           req.CacheDir,
+		MountArgs:          req.MountArgs,
+		LocalSocket:        req.LocalSocket,
+		MemoryLimitMb:      req.MemoryLimitMB,
+		CpuLimitMillicores: req.CPULimitMillicores,
+	}
+	if c := req.Credentials; c != nil {
+		msg.Credentials = &mountv1.MountCredentials{
+			TlsCert:             c.TLSCert,
+			TlsKey:              c.TLSKey,
+			CaCert:              c.CACert,
+			FilerSigningKey:     c.FilerSigningKey,
+			FilerReadSigningKey: c.FilerReadSigningKey,
+		}
+	}
+	return msg
+}
+
+func mountRequestFromProto(msg *mountv1.MountRequest) *MountRequest {
+	req := &MountRequest{
+		VolumeID:           msg.GetVolumeId(),
+		TargetPath:         msg.GetTargetPath(),
+		CacheDir:           msg.GetCacheDir(),
+		MountArgs:          msg.GetMountArgs(),
+		LocalSocket:        msg.GetLocalSocket(),
+		MemoryLimitMB:      msg.GetMemoryLimitMb(),
+		CPULimitMillicores: msg.GetCpuLimitMillicores(),
+	}
+	if c := msg.GetCredentials(); c != nil {
+		req.Credentials = &MountCredentials{
+			TLSCert:             c.GetTlsCert(),
+			TLSKey:              c.GetTlsKey(),
+			CACert:              c.GetCaCert(),
+			FilerSigningKey:     c.GetFilerSigningKey(),
+			FilerReadSigningKey: c.GetFilerReadSigningKey(),
+		}
+	}
+	return req
+}
+
+func mountStatusToProto(s *MountStatus) *mountv1.MountStatus {
+	msg := &mountv1.MountStatus{
+		VolumeId:           s.VolumeID,
+		TargetPath:         s.TargetPath,
+		CacheDir:           s.CacheDir,
+		LocalSocket:        s.LocalSocket,
+		MountArgs:          s.MountArgs,
+		Pid:                int32(s.Pid),
+		UptimeSeconds:      s.UptimeSeconds,
+		RestartCount:       int32(s.RestartCount),
+		Exited:             s.Exited,
+		OomKilled:          s.OOMKilled,
+		MemoryLimitMb:      s.MemoryLimitMB,
+		CpuLimitMillicores: s.CPULimitMillicores,
+		CgroupDir:          s.CgroupDir,
+	}
+	if !s.StartTime.IsZero() {
+		msg.StartTime = timestamppb.New(s.StartTime)
+	}
+	return msg
+}
+
+func mountStatusFromProto(msg *mountv1.MountStatus) *MountStatus {
+	s := &MountStatus{
+		VolumeID:           msg.GetVolumeId(),
+		TargetPath:         msg.GetTargetPath(),
+		CacheDir:           msg.GetCacheDir(),
+		LocalSocket:        msg.GetLocalSocket(),
+		MountArgs:          msg.GetMountArgs(),
+		Pid:                int(msg.GetPid()),
+		UptimeSeconds:      msg.GetUptimeSeconds(),
+		RestartCount:       int(msg.GetRestartCount()),
+		Exited:             msg.GetExited(),
+		OOMKilled:          msg.GetOomKilled(),
+		MemoryLimitMB:      msg.GetMemoryLimitMb(),
+		CPULimitMillicores: msg.GetCpuLimitMillicores(),
+		CgroupDir:          msg.GetCgroupDir(),
+	}
+	if msg.GetStartTime() != nil {
+		s.StartTime = msg.GetStartTime().AsTime()
+	}
+	return s
+}
+
+var eventTypes = map[EventType]mountv1.EventType{
+	EventStarted:   mountv1.EventType_EVENT_TYPE_STARTED,
+	EventExited:    mountv1.EventType_EVENT_TYPE_EXITED,
+	EventRestarted: mountv1.EventType_EVENT_TYPE_RESTARTED,
+}
+
+func mountEventToProto(e *MountEvent) *mountv1.MountEvent {
+	return &mountv1.MountEvent{
+		Type:         eventTypes[e.Type],
+		VolumeId:     e.VolumeID,
+		TargetPath:   e.TargetPath,
+		Pid:          int32(e.Pid),
+		Time:         timestamppb.New(e.Time),
+		RestartCount: int32(e.RestartCount),
+		Stopped:      e.Stopped,
+		Restarting:   e.Restarting,
+		OomKilled:    e.OOMKilled,
+	}
+}
+
+func mountEventFromProto(msg *mountv1.MountEvent) *MountEvent {
+	e := &MountEvent{
+		VolumeID:     msg.GetVolumeId(),
+		TargetPath:   msg.GetTargetPath(),
+		Pid:          int(msg.GetPid()),
+		Time:         msg.GetTime().AsTime(),
+		RestartCount: int(msg.GetRestartCount()),
+		Stopped:      msg.GetStopped(),
+		Restarting:   msg.GetRestarting(),
+		OOMKilled:    msg.GetOomKilled(),
+	}
+	for eventType, protoType := range eventTypes {
+		if protoType == msg.GetType() {
+			e.Type = eventType
+		}
+	}
+	return e
+}
@@ -0,0 +1,41 @@
+package mountmanager
+
+import (
+	"os"
+	"strconv"
+)
+
+// hostScopeSlice is the systemd slice of the host the scopes of weed mount
+// processes are created in.
+const hostScopeSlice = "seaweedfs-mount.slice"
+
+// hostScopeCommand returns the command running weed with args in a
+// transient systemd scope of the host, limited to memoryLimitMB and
+// cpuLimitMillicores (0 meaning unlimited).
+//
+// The scope moves the process out of the cgroup of the mount service
+// container, so that the container runtime does not kill it together with
+// the container. systemd-run is the host's, reached by entering the mount
+// namespace of the host's init; it then enters the mount namespace of the
+// mount service again, so that weed runs from the container image and sees
+// the same paths as the service. Every step execs the next, so the started
+// process is the weed process itself. This needs the host's pid namespace
+// (hostPID) and a privileged container.
+func hostScopeCommand(weedBinary string, args []string, volumeID string, memoryLimitMB, cpuLimitMillicores int64) (string, []string) {
+	command := []string{
+		"-t", "1", "-m", "--",
+		"systemd-run", "--scope", "--collect", "--quiet",
+		"--slice=" + hostScopeSlice,
+		"--description=weed mount of volume " + volumeID,
+	}
+	if memoryLimitMB > 0 {
+		command = append(command, "--property=MemoryMax="+strconv.FormatInt(memoryLimitMB*1024*1024, 10))
+	}
+	if cpuLimitMillicores > 0 {
+		// whole percents of a CPU, at least 1% given the minimum of 10
+		// millicores
+		command = append(command, "--property=CPUQuota="+strconv.FormatInt(cpuLimitMillicores/10, 10)+"%")
+	}
+	command = append(command, "--", "nsenter", "-t", strconv.Itoa(os.Getpid()), "-m", "--", weedBinary)
+	return "nsenter", append(command, args...)
+}
@@ -0,0 +1,29 @@
+package mountmanager
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestHostScopeCommandEndsWithWeedCommandLine(t *testing.T) {
+	args := []string{"mount", "-dir=/staging/vol-1"}
+	command, commandArgs := hostScopeCommand("/usr/bin/weed", args, "vol-1", 512, 250)
+	if command != "nsenter" {
+		t.Fatalf("command = %q, want nsenter", command)
+	}
+	// every step execs the next, the process ends up running weed with the
+	// journaled arguments, as findMountProcess expects
+	tail := append([]string{"/usr/bin/weed"}, args...)
+	if !slices.Equal(commandArgs[len(commandArgs)-len(tail):], tail) {
+		t.Fatalf("command line %q does not end with %q", commandArgs, tail)
+	}
+	for _, arg := range []string{"--scope", "--slice=" + hostScopeSlice, "--property=MemoryMax=536870912", "--property=CPUQuota=25%"} {
+		if !slices.Contains(commandArgs, arg) {
+			t.Fatalf("command line %q lacks %s", commandArgs, arg)
+		}
+	}
+	if _, unlimited := hostScopeCommand("/usr/bin/weed", args, "vol-1", 0, 0); slices.ContainsFunc(unlimited, func(arg string) bool { return strings.HasPrefix(arg, "--property=") }) {
+		t.Fatalf("unlimited scope has properties: %q", unlimited)
+	}
+}
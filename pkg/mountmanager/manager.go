@@ -7,8 +7,10 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -20,16 +22,43 @@ var kubeMounter = mount.New("")
 
 // Manager owns weed mount processes and exposes helpers to start and stop them.
 type Manager struct {
-	weedBinary string
+	weedBinary     string
+	stateFile      string
+	restartCrashed bool
+	maxRestarts    int
+	// cgroupRoot is the cgroup the weed mount processes get their own
+	// sub-group in, empty when they are not run in cgroups
+	cgroupRoot string
+	// hostScopes runs the weed mount processes in systemd scopes of the host
+	hostScopes bool
 
 	mu     sync.Mutex
 	mounts map[string]*mountEntry
 	locks  *keyMutex
+	events eventHub
 }
 
 // Config configures a Manager instance.
 type Config struct {
 	WeedBinary string
+	// StateFile is where the manager journals its mounts, so that a
+	// restarted manager re-adopts the weed mount processes that are still
+	// running. No state is kept when empty.
+	StateFile string
+	// RestartCrashed makes the manager restart weed mount processes that
+	// exit without being asked to, with exponential backoff, until
+	// MaxRestarts quick crashes in a row (DefaultMaxRestarts if 0).
+	RestartCrashed bool
+	MaxRestarts    int
+	// Cgroups runs every weed mount process in its own cgroup v2 sub-group
+	// of the manager's cgroup, limited by the resource limits of its mount
+	// request. The manager runs without when cgroup v2 is not available.
+	Cgroups bool
+	// HostScopes runs every weed mount process in a transient systemd scope
+	// of the host, limited by the resource limits of its mount request, so
+	// that it outlives the mount service container and is re-adopted by the
+	// next one through StateFile. It replaces Cgroups.
+	HostScopes bool
 }
 
 // NewManager returns a Manager ready to accept mount requests.
@@ -38,11 +67,37 @@ func NewManager(cfg Config) *Manager {
 	if binary == "" {
 		binary = DefaultWeedBinary
 	}
-	return &Manager{
-		weedBinary: binary,
-		mounts:     make(map[string]*mountEntry),
-		locks:      newKeyMutex(),
+	maxRestarts := cfg.MaxRestarts
+	if maxRestarts <= 0 {
+		maxRestarts = DefaultMaxRestarts
+	}
+	m := &Manager{
+		weedBinary:     binary,
+		stateFile:      cfg.StateFile,
+		restartCrashed: cfg.RestartCrashed,
+		maxRestarts:    maxRestarts,
+		mounts:         make(map[string]*mountEntry),
+		locks:          newKeyMutex(),
+		hostScopes:     cfg.HostScopes,
+	}
+	if cfg.HostScopes {
+		glog.Infof("running weed mount processes in systemd scopes of the host below %s", hostScopeSlice)
+		if cfg.Cgroups {
+			glog.Warningf("not creating cgroups, the host scopes enforce the resource limits")
+		}
+	} else if cfg.Cgroups {
+		root, err := setupCgroups()
+		if err != nil {
+			glog.Warningf("running weed mount processes without cgroups: %v", err)
+		} else {
+			glog.Infof("running weed mount processes in cgroups below %s", root)
+			m.cgroupRoot = root
+		}
 	}
+	if m.stateFile != "" {
+		m.restoreState()
+	}
+	return m
 }
 
 // Mount starts a weed mount process using the provided request.
@@ -87,7 +142,9 @@ func (m *Manager) Mount(req *MountRequest) (*MountResponse, error) {
 
 	m.mu.Lock()
 	m.mounts[req.VolumeID] = entry
+	m.saveStateLocked()
 	m.mu.Unlock()
+	m.publish(entry.event(EventStarted, entry.startTime))
 
 	// Proactively clear the entry once the weed mount process exits,
 	// even if Unmount is never called. Without this, a process that
@@ -101,16 +158,27 @@ func (m *Manager) Mount(req *MountRequest) (*MountResponse, error) {
 }
 
 // watchProcessExit removes the entry for volumeID once its weed mount
-// process has finished exiting. It is safe to run concurrently with
-// Unmount: if Unmount has already removed the entry (or replaced it
-// with a fresh mount), the identity check ensures we leave the new
-// entry alone.
+// process has finished exiting, unless the supervisor restarts it. It is
+// safe to run concurrently with Unmount: if Unmount has already removed
+// the entry (or replaced it with a fresh mount), the identity check
+// ensures we leave the new entry alone.
 func (m *Manager) watchProcessExit(volumeID string, entry *mountEntry) {
 	<-entry.process.done
+	now := time.Now()
+	event := entry.event(EventExited, now)
+	event.Stopped = entry.process.stopRequested()
+	event.OOMKilled = entry.process.oomKilled.Load()
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	defer func() { m.publish(event) }()
 	if existing, ok := m.mounts[volumeID]; ok && existing == entry {
+		if m.superviseExit(volumeID, entry, now) {
+			event.Restarting = true
+			return
+		}
 		delete(m.mounts, volumeID)
+		m.saveStateLocked()
 		// See removeMount: do not delete the per-volume lock — a
 		// concurrent Mount/Unmount may still be holding it.
 		glog.Infof("removed mount entry for volume %s after weed mount process exited (target: %s)", volumeID, entry.targetPath)
@@ -145,9 +213,7 @@ func (m *Manager) Unmount(req *UnmountRequest) (*UnmountResponse, error) {
 	}
 
 	// Remove cache dir only after process has been successfully stopped
-	if err := os.RemoveAll(entry.cacheDir); err != nil {
-		glog.Warningf("failed to remove cache dir %s for volume %s: %v", entry.cacheDir, req.VolumeID, err)
-	}
+	removeCacheDir(req.VolumeID, entry.cacheDir)
 
 	// Only remove from state after all cleanup operations succeeded
 	m.removeMount(req.VolumeID)
@@ -156,6 +222,37 @@ func (m *Manager) Unmount(req *UnmountRequest) (*UnmountResponse, error) {
 	return &UnmountResponse{}, nil
 }
 
+// removeCacheDir removes the cache directory of a mount, together with the
+// credentials written into it.
+func removeCacheDir(volumeID, cacheDir string) {
+	if err := os.RemoveAll(cacheDir); err != nil {
+		glog.Warningf("failed to remove cache dir %s for volume %s: %v", cacheDir, volumeID, err)
+	}
+}
+
+// List returns the status of every mount, ordered by volume ID.
+func (m *Manager) List() []MountStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	mounts := make([]MountStatus, 0, len(m.mounts))
+	for _, entry := range m.mounts {
+		mounts = append(mounts, entry.status(now))
+	}
+	slices.SortFunc(mounts, func(a, b MountStatus) int { return strings.Compare(a.VolumeID, b.VolumeID) })
+	return mounts
+}
+
+// Status returns the status of the mount of volumeID, or ErrVolumeNotMounted.
+func (m *Manager) Status(volumeID string) (*MountStatus, error) {
+	entry := m.getMount(volumeID)
+	if entry == nil {
+		return nil, ErrVolumeNotMounted
+	}
+	status := entry.status(time.Now())
+	return &status, nil
+}
+
 func (m *Manager) getMount(volumeID string) *mountEntry {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -165,8 +262,11 @@ func (m *Manager) getMount(volumeID string) *mountEntry {
 func (m *Manager) removeMount(volumeID string) *mountEntry {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	entry := m.mounts[volumeID]
-	delete(m.mounts, volumeID)
+	entry, ok := m.mounts[volumeID]
+	if ok {
+		delete(m.mounts, volumeID)
+		m.saveStateLocked()
+	}
 	// Intentionally do NOT delete the per-volume lock here. If a caller
 	// is still holding the lock from m.locks.get(volumeID), deleting it
 	// would let a concurrent caller receive a brand-new lock from the
@@ -201,20 +301,47 @@ func (m *Manager) startMount(req *MountRequest) (*mountEntry, error) {
 		return nil, errors.New("mountArgs is required")
 	}
 
-	process, err := startWeedMountProcess(m.weedBinary, args, targetPath, req.VolumeID)
+	if req.Credentials != nil {
+		configDir, err := writeCredentials(cacheDir, req.Credentials)
+		if err != nil {
+			return nil, fmt.Errorf("writing credentials: %w", err)
+		}
+		// -config_dir is a global flag of weed, it goes before the command
+		args = append([]string{"-config_dir=" + configDir}, args...)
+	}
+
+	process, err := m.startProcess(req.VolumeID, args, targetPath, cacheDir, req.MemoryLimitMB, req.CPULimitMillicores)
 	if err != nil {
 		return nil, err
 	}
 
 	return &mountEntry{
-		volumeID:    req.VolumeID,
-		targetPath:  targetPath,
-		cacheDir:    cacheDir,
-		localSocket: localSocket,
-		process:     process,
+		volumeID:           req.VolumeID,
+		targetPath:         targetPath,
+		cacheDir:           cacheDir,
+		localSocket:        localSocket,
+		args:               args,
+		memoryLimitMB:      req.MemoryLimitMB,
+		cpuLimitMillicores: req.CPULimitMillicores,
+		startTime:          time.Now(),
+		process:            process,
 	}, nil
 }
 
+// startProcess starts the weed mount process of a mount, in its own cgroup
+// or host scope if the manager uses them.
+func (m *Manager) startProcess(volumeID string, args []string, targetPath, cacheDir string, memoryLimitMB, cpuLimitMillicores int64) (*weedMountProcess, error) {
+	if m.hostScopes {
+		command, commandArgs := hostScopeCommand(m.weedBinary, args, volumeID, memoryLimitMB, cpuLimitMillicores)
+		return startWeedMountProcess(command, commandArgs, targetPath, volumeID, mountLogPath(cacheDir), "")
+	}
+	cgroupDir, err := m.mountCgroup(volumeID, memoryLimitMB, cpuLimitMillicores)
+	if err != nil {
+		return nil, err
+	}
+	return startWeedMountProcess(m.weedBinary, args, targetPath, volumeID, mountLogPath(cacheDir), cgroupDir)
+}
+
 func ensureTargetClean(targetPath string) error {
 	// Use IsLikelyNotMountPoint instead of deprecated IsMountPoint
 	notMnt, err := kubeMounter.IsLikelyNotMountPoint(targetPath)
@@ -264,12 +391,67 @@ type mountEntry struct {
 	targetPath  string
 	cacheDir    string
 	localSocket string
-	process     *weedMountProcess
+	// args are the arguments weed was started with
+	args []string
+	// memoryLimitMB and cpuLimitMillicores are the resource limits of the
+	// mount request
+	memoryLimitMB      int64
+	cpuLimitMillicores int64
+	// startTime is when process was started
+	startTime    time.Time
+	restartCount int
+	// consecutiveCrashes counts the quick crashes the supervisor restarted
+	// the mount after in a row
+	consecutiveCrashes int
+	process            *weedMountProcess
+}
+
+// status describes the entry at now.
+func (e *mountEntry) status(now time.Time) MountStatus {
+	exited := false
+	select {
+	case <-e.process.exited:
+		exited = true
+	default:
+	}
+	status := MountStatus{
+		VolumeID:     e.volumeID,
+		TargetPath:   e.targetPath,
+		CacheDir:     e.cacheDir,
+		LocalSocket:  e.localSocket,
+		MountArgs:    e.args,
+		StartTime:    e.startTime,
+		RestartCount: e.restartCount,
+		Exited:       exited,
+		OOMKilled:    e.process.oomKilled.Load(),
+
+		MemoryLimitMB:      e.memoryLimitMB,
+		CPULimitMillicores: e.cpuLimitMillicores,
+		CgroupDir:          e.process.cgroupDir,
+	}
+	if e.process.process != nil {
+		status.Pid = e.process.process.Pid
+	}
+	if !e.startTime.IsZero() {
+		status.UptimeSeconds = int64(now.Sub(e.startTime).Seconds())
+	}
+	return status
 }
 
 type weedMountProcess struct {
-	cmd    *exec.Cmd
-	target string
+	// process is the weed mount process, started by this manager or
+	// adopted from a previous one
+	process *os.Process
+	target  string
+	// cgroupDir is the cgroup the process runs in, if any. It is removed
+	// once the process has exited.
+	cgroupDir string
+	// oomKilled is set before exited is closed if the kernel killed the
+	// process for exceeding the memory limit of its cgroup
+	oomKilled atomic.Bool
+	// stopping is set once the process is asked to stop, so that its exit
+	// is not taken for a crash
+	stopping atomic.Bool
 	// exited is closed as soon as cmd.Wait() returns, so callers can
 	// detect that the weed mount process is gone without waiting for
 	// the post-exit FUSE unmount step.
@@ -279,37 +461,59 @@ type weedMountProcess struct {
 	done chan struct{}
 }
 
-func startWeedMountProcess(command string, args []string, target string, volumeID string) (*weedMountProcess, error) {
+func startWeedMountProcess(command string, args []string, target string, volumeID string, logPath string, cgroupDir string) (*weedMountProcess, error) {
 	cmd := exec.Command(command, args...)
 
-	// Capture stdout/stderr and log with volume ID prefix for better debugging
-	stdoutPipe, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	// The cgroup belongs to the process once it runs, which removes it when
+	// it exits; until then it is removed here on failure
+	started := false
+	defer func() {
+		if cgroupDir != "" && !started {
+			removeMountCgroup(cgroupDir)
+		}
+	}()
+
+	if cgroupDir != "" {
+		dir, err := os.Open(cgroupDir)
+		if err != nil {
+			return nil, fmt.Errorf("opening cgroup: %w", err)
+		}
+		defer dir.Close()
+		if err := startInCgroup(cmd, dir); err != nil {
+			return nil, err
+		}
 	}
-	stderrPipe, err := cmd.StderrPipe()
+
+	// The output goes to a file rather than a pipe: weed mount outlives a
+	// restart of the mount service, and would be killed by SIGPIPE writing
+	// to a pipe nobody reads anymore
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
 	if err != nil {
-		return nil, fmt.Errorf("creating stderr pipe: %w", err)
+		return nil, fmt.Errorf("creating log file: %w", err)
 	}
+	defer logFile.Close()
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
 
 	glog.V(0).Infof("[%s] Starting weed mount: %s %s", volumeID, command, strings.Join(args, " "))
 
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("starting weed mount: %w", err)
 	}
-
-	// Forward stdout/stderr with volume ID prefix for better debugging
-	go forwardLogs(stdoutPipe, volumeID, "stdout")
-	go forwardLogs(stderrPipe, volumeID, "stderr")
+	started = true
 
 	process := &weedMountProcess{
-		cmd:    cmd,
-		target: target,
-		exited: make(chan struct{}),
-		done:   make(chan struct{}),
+		process:   cmd.Process,
+		target:    target,
+		cgroupDir: cgroupDir,
+		exited:    make(chan struct{}),
+		done:      make(chan struct{}),
 	}
 
-	go process.wait()
+	go process.wait(cmd.Wait)
+
+	// Forward the output with volume ID prefix for better debugging
+	go followLogs(logPath, 0, volumeID, process.done)
 
 	if err := waitForMount(target, 10*time.Second); err != nil {
 		if stopErr := process.stop(); stopErr != nil {
@@ -321,11 +525,17 @@ func startWeedMountProcess(command string, args []string, target string, volumeI
 	return process, nil
 }
 
-func (p *weedMountProcess) wait() {
-	if err := p.cmd.Wait(); err != nil {
-		glog.Errorf("weed mount exit (pid: %d, target: %s): %v", p.cmd.Process.Pid, p.target, err)
+// wait waits for the process to exit through waitFn, which is cmd.Wait for
+// processes started by this manager and polls adopted ones.
+func (p *weedMountProcess) wait(waitFn func() error) {
+	if err := waitFn(); err != nil {
+		glog.Errorf("weed mount exit (pid: %d, target: %s): %v", p.process.Pid, p.target, err)
 	} else {
-		glog.Infof("weed mount exit (pid: %d, target: %s)", p.cmd.Process.Pid, p.target)
+		glog.Infof("weed mount exit (pid: %d, target: %s)", p.process.Pid, p.target)
+	}
+	if p.cgroupDir != "" && cgroupOOMKills(p.cgroupDir) > 0 {
+		glog.Errorf("weed mount was OOM-killed: it exceeded the memory limit of its cgroup %s (pid: %d, target: %s)", p.cgroupDir, p.process.Pid, p.target)
+		p.oomKilled.Store(true)
 	}
 
 	// Signal exit immediately so Manager.Mount can detect a dead
@@ -335,12 +545,21 @@ func (p *weedMountProcess) wait() {
 	// Brief delay to allow FUSE cleanup and pending I/O to complete before unmounting
 	time.Sleep(100 * time.Millisecond)
 	_ = kubeMounter.Unmount(p.target)
+	if p.cgroupDir != "" {
+		removeMountCgroup(p.cgroupDir)
+	}
 
 	close(p.done)
 }
 
+// stopRequested reports whether stop has been called.
+func (p *weedMountProcess) stopRequested() bool {
+	return p.stopping.Load()
+}
+
 func (p *weedMountProcess) stop() error {
-	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+	p.stopping.Store(true)
+	if err := p.process.Signal(syscall.SIGTERM); err != nil {
 		glog.Warningf("sending SIGTERM to weed mount failed: %v", err)
 	}
 
@@ -350,7 +569,7 @@ func (p *weedMountProcess) stop() error {
 	case <-time.After(5 * time.Second):
 	}
 
-	if err := p.cmd.Process.Kill(); err != nil {
+	if err := p.process.Kill(); err != nil {
 		glog.Warningf("killing weed mount failed: %v", err)
 	}
 
@@ -383,13 +602,46 @@ func waitForMount(path string, timeout time.Duration) error {
 	}
 }
 
-// forwardLogs reads from a pipe and logs each line with a volume ID prefix.
-func forwardLogs(pipe io.ReadCloser, volumeID string, stream string) {
-	scanner := bufio.NewScanner(pipe)
-	for scanner.Scan() {
-		glog.Infof("[%s] %s: %s", volumeID, stream, scanner.Text())
+// followLogs logs each line written to the log file of a weed mount process
+// from offset on with a volume ID prefix, until done is closed.
+func followLogs(logPath string, offset int64, volumeID string, done <-chan struct{}) {
+	file, err := os.Open(logPath)
+	if err != nil {
+		glog.Warningf("[%s] error opening log: %v", volumeID, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		glog.Warningf("[%s] error seeking log: %v", volumeID, err)
+		return
 	}
-	if err := scanner.Err(); err != nil {
-		glog.Warningf("[%s] error reading %s: %v", volumeID, stream, err)
+
+	reader := bufio.NewReader(file)
+	var partial string
+	exited := false
+	for {
+		line, err := reader.ReadString('\n')
+		partial += line
+		if err == nil {
+			glog.Infof("[%s] %s", volumeID, strings.TrimSuffix(partial, "\n"))
+			partial = ""
+			continue
+		}
+		if err != io.EOF {
+			glog.Warningf("[%s] error reading log: %v", volumeID, err)
+			return
+		}
+		if exited {
+			// everything the process wrote has been read
+			if partial != "" {
+				glog.Infof("[%s] %s", volumeID, partial)
+			}
+			return
+		}
+		select {
+		case <-done:
+			exited = true
+		case <-time.After(200 * time.Millisecond):
+		}
 	}
 }
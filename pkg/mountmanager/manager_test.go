@@ -101,3 +101,32 @@ func TestWatchProcessExitLeavesReplacedEntry(t *testing.T) {
 		t.Fatalf("expected new entry preserved, got %v", got)
 	}
 }
+
+func TestListAndStatus(t *testing.T) {
+	m := NewManager(Config{})
+
+	started := time.Now().Add(-time.Minute)
+	running := &weedMountProcess{exited: make(chan struct{}), done: make(chan struct{})}
+	exited := &weedMountProcess{exited: make(chan struct{}), done: make(chan struct{})}
+	close(exited.exited)
+	m.mounts["vol-b"] = &mountEntry{volumeID: "vol-b", targetPath: "/b", args: []string{"mount"}, startTime: started, process: running}
+	m.mounts["vol-a"] = &mountEntry{volumeID: "vol-a", targetPath: "/a", restartCount: 2, process: exited}
+
+	mounts := m.List()
+	if len(mounts) != 2 || mounts[0].VolumeID != "vol-a" || mounts[1].VolumeID != "vol-b" {
+		t.Fatalf("List() = %+v, want vol-a and vol-b in order", mounts)
+	}
+	if !mounts[0].Exited || mounts[0].RestartCount != 2 {
+		t.Errorf("vol-a status = %+v, want exited with 2 restarts", mounts[0])
+	}
+	if mounts[1].Exited || mounts[1].UptimeSeconds < 60 || mounts[1].TargetPath != "/b" {
+		t.Errorf("vol-b status = %+v, want running for a minute at /b", mounts[1])
+	}
+
+	if status, err := m.Status("vol-b"); err != nil || status.VolumeID != "vol-b" {
+		t.Fatalf("Status(vol-b) = %+v, %v", status, err)
+	}
+	if _, err := m.Status("vol-c"); err != ErrVolumeNotMounted {
+		t.Fatalf("Status(vol-c) error = %v, want ErrVolumeNotMounted", err)
+	}
+}
@@ -0,0 +1,4 @@
+// Package mountv1 is version 1 of the gRPC API of the mount service.
+package mountv1
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative ../mountv1/mount.proto
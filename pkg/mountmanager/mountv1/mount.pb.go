@@ -0,0 +1,977 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: mountv1/mount.proto
+
+package mountv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED EventType = 0
+	// A weed mount process was started for a new mount.
+	EventType_EVENT_TYPE_STARTED EventType = 1
+	// A weed mount process exited.
+	EventType_EVENT_TYPE_EXITED EventType = 2
+	// The supervisor restarted a crashed weed mount process.
+	EventType_EVENT_TYPE_RESTARTED EventType = 3
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_STARTED",
+		2: "EVENT_TYPE_EXITED",
+		3: "EVENT_TYPE_RESTARTED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED": 0,
+		"EVENT_TYPE_STARTED":     1,
+		"EVENT_TYPE_EXITED":      2,
+		"EVENT_TYPE_RESTARTED":   3,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_mountv1_mount_proto_enumTypes[0].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_mountv1_mount_proto_enumTypes[0]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{0}
+}
+
+// MountCredentials are the filer credentials of a volume, taken from the
+// CSI secrets of the volume. PEM encoded certificates and keys.
+type MountCredentials struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	TlsCert string                 `protobuf:"bytes,1,opt,name=tls_cert,json=tlsCert,proto3" json:"tls_cert,omitempty"`
+	TlsKey  string                 `protobuf:"bytes,2,opt,name=tls_key,json=tlsKey,proto3" json:"tls_key,omitempty"`
+	CaCert  string                 `protobuf:"bytes,3,opt,name=ca_cert,json=caCert,proto3" json:"ca_cert,omitempty"`
+	// jwt.filer_signing key
+	FilerSigningKey string `protobuf:"bytes,4,opt,name=filer_signing_key,json=filerSigningKey,proto3" json:"filer_signing_key,omitempty"`
+	// jwt.filer_signing.read key
+	FilerReadSigningKey string `protobuf:"bytes,5,opt,name=filer_read_signing_key,json=filerReadSigningKey,proto3" json:"filer_read_signing_key,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *MountCredentials) Reset() {
+	*x = MountCredentials{}
+	mi := &file_mountv1_mount_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MountCredentials) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MountCredentials) ProtoMessage() {}
+
+func (x *MountCredentials) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MountCredentials.ProtoReflect.Descriptor instead.
+func (*MountCredentials) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MountCredentials) GetTlsCert() string {
+	if x != nil {
+		return x.TlsCert
+	}
+	return ""
+}
+
+func (x *MountCredentials) GetTlsKey() string {
+	if x != nil {
+		return x.TlsKey
+	}
+	return ""
+}
+
+func (x *MountCredentials) GetCaCert() string {
+	if x != nil {
+		return x.CaCert
+	}
+	return ""
+}
+
+func (x *MountCredentials) GetFilerSigningKey() string {
+	if x != nil {
+		return x.FilerSigningKey
+	}
+	return ""
+}
+
+func (x *MountCredentials) GetFilerReadSigningKey() string {
+	if x != nil {
+		return x.FilerReadSigningKey
+	}
+	return ""
+}
+
+type MountRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId    string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	TargetPath  string                 `protobuf:"bytes,2,opt,name=target_path,json=targetPath,proto3" json:"target_path,omitempty"`
+	CacheDir    string                 `protobuf:"bytes,3,opt,name=cache_dir,json=cacheDir,proto3" json:"cache_dir,omitempty"`
+	MountArgs   []string               `protobuf:"bytes,4,rep,name=mount_args,json=mountArgs,proto3" json:"mount_args,omitempty"`
+	LocalSocket string                 `protobuf:"bytes,5,opt,name=local_socket,json=localSocket,proto3" json:"local_socket,omitempty"`
+	// Override the settings of the node's security.toml for this mount.
+	Credentials *MountCredentials `protobuf:"bytes,6,opt,name=credentials,proto3" json:"credentials,omitempty"`
+	// Resource limits of the weed mount process, 0 meaning unlimited.
+	MemoryLimitMb      int64 `protobuf:"varint,7,opt,name=memory_limit_mb,json=memoryLimitMb,proto3" json:"memory_limit_mb,omitempty"`
+	CpuLimitMillicores int64 `protobuf:"varint,8,opt,name=cpu_limit_millicores,json=cpuLimitMillicores,proto3" json:"cpu_limit_millicores,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *MountRequest) Reset() {
+	*x = MountRequest{}
+	mi := &file_mountv1_mount_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MountRequest) ProtoMessage() {}
+
+func (x *MountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MountRequest.ProtoReflect.Descriptor instead.
+func (*MountRequest) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MountRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *MountRequest) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+func (x *MountRequest) GetCacheDir() string {
+	if x != nil {
+		return x.CacheDir
+	}
+	return ""
+}
+
+func (x *MountRequest) GetMountArgs() []string {
+	if x != nil {
+		return x.MountArgs
+	}
+	return nil
+}
+
+func (x *MountRequest) GetLocalSocket() string {
+	if x != nil {
+		return x.LocalSocket
+	}
+	return ""
+}
+
+func (x *MountRequest) GetCredentials() *MountCredentials {
+	if x != nil {
+		return x.Credentials
+	}
+	return nil
+}
+
+func (x *MountRequest) GetMemoryLimitMb() int64 {
+	if x != nil {
+		return x.MemoryLimitMb
+	}
+	return 0
+}
+
+func (x *MountRequest) GetCpuLimitMillicores() int64 {
+	if x != nil {
+		return x.CpuLimitMillicores
+	}
+	return 0
+}
+
+type MountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LocalSocket   string                 `protobuf:"bytes,1,opt,name=local_socket,json=localSocket,proto3" json:"local_socket,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MountResponse) Reset() {
+	*x = MountResponse{}
+	mi := &file_mountv1_mount_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MountResponse) ProtoMessage() {}
+
+func (x *MountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MountResponse.ProtoReflect.Descriptor instead.
+func (*MountResponse) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MountResponse) GetLocalSocket() string {
+	if x != nil {
+		return x.LocalSocket
+	}
+	return ""
+}
+
+type UnmountRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnmountRequest) Reset() {
+	*x = UnmountRequest{}
+	mi := &file_mountv1_mount_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnmountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnmountRequest) ProtoMessage() {}
+
+func (x *UnmountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnmountRequest.ProtoReflect.Descriptor instead.
+func (*UnmountRequest) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UnmountRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+type UnmountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnmountResponse) Reset() {
+	*x = UnmountResponse{}
+	mi := &file_mountv1_mount_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnmountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnmountResponse) ProtoMessage() {}
+
+func (x *UnmountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnmountResponse.ProtoReflect.Descriptor instead.
+func (*UnmountResponse) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{4}
+}
+
+type ListRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_mountv1_mount_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{5}
+}
+
+type ListResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mounts        []*MountStatus         `protobuf:"bytes,1,rep,name=mounts,proto3" json:"mounts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_mountv1_mount_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListResponse) GetMounts() []*MountStatus {
+	if x != nil {
+		return x.Mounts
+	}
+	return nil
+}
+
+type StatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId      string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	mi := &file_mountv1_mount_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StatusRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+// MountStatus describes a mount and its weed mount process.
+type MountStatus struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	VolumeId    string                 `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	TargetPath  string                 `protobuf:"bytes,2,opt,name=target_path,json=targetPath,proto3" json:"target_path,omitempty"`
+	CacheDir    string                 `protobuf:"bytes,3,opt,name=cache_dir,json=cacheDir,proto3" json:"cache_dir,omitempty"`
+	LocalSocket string                 `protobuf:"bytes,4,opt,name=local_socket,json=localSocket,proto3" json:"local_socket,omitempty"`
+	MountArgs   []string               `protobuf:"bytes,5,rep,name=mount_args,json=mountArgs,proto3" json:"mount_args,omitempty"`
+	Pid         int32                  `protobuf:"varint,6,opt,name=pid,proto3" json:"pid,omitempty"`
+	// When the current weed mount process was started.
+	StartTime     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	UptimeSeconds int64                  `protobuf:"varint,8,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	// How often the weed mount process has been restarted.
+	RestartCount int32 `protobuf:"varint,9,opt,name=restart_count,json=restartCount,proto3" json:"restart_count,omitempty"`
+	// Set once the weed mount process has exited.
+	Exited bool `protobuf:"varint,10,opt,name=exited,proto3" json:"exited,omitempty"`
+	// Set when the kernel killed the weed mount process for exceeding its
+	// memory limit.
+	OomKilled          bool   `protobuf:"varint,11,opt,name=oom_killed,json=oomKilled,proto3" json:"oom_killed,omitempty"`
+	MemoryLimitMb      int64  `protobuf:"varint,12,opt,name=memory_limit_mb,json=memoryLimitMb,proto3" json:"memory_limit_mb,omitempty"`
+	CpuLimitMillicores int64  `protobuf:"varint,13,opt,name=cpu_limit_millicores,json=cpuLimitMillicores,proto3" json:"cpu_limit_millicores,omitempty"`
+	CgroupDir          string `protobuf:"bytes,14,opt,name=cgroup_dir,json=cgroupDir,proto3" json:"cgroup_dir,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *MountStatus) Reset() {
+	*x = MountStatus{}
+	mi := &file_mountv1_mount_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MountStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MountStatus) ProtoMessage() {}
+
+func (x *MountStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MountStatus.ProtoReflect.Descriptor instead.
+func (*MountStatus) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MountStatus) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *MountStatus) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+func (x *MountStatus) GetCacheDir() string {
+	if x != nil {
+		return x.CacheDir
+	}
+	return ""
+}
+
+func (x *MountStatus) GetLocalSocket() string {
+	if x != nil {
+		return x.LocalSocket
+	}
+	return ""
+}
+
+func (x *MountStatus) GetMountArgs() []string {
+	if x != nil {
+		return x.MountArgs
+	}
+	return nil
+}
+
+func (x *MountStatus) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *MountStatus) GetStartTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *MountStatus) GetUptimeSeconds() int64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *MountStatus) GetRestartCount() int32 {
+	if x != nil {
+		return x.RestartCount
+	}
+	return 0
+}
+
+func (x *MountStatus) GetExited() bool {
+	if x != nil {
+		return x.Exited
+	}
+	return false
+}
+
+func (x *MountStatus) GetOomKilled() bool {
+	if x != nil {
+		return x.OomKilled
+	}
+	return false
+}
+
+func (x *MountStatus) GetMemoryLimitMb() int64 {
+	if x != nil {
+		return x.MemoryLimitMb
+	}
+	return 0
+}
+
+func (x *MountStatus) GetCpuLimitMillicores() int64 {
+	if x != nil {
+		return x.CpuLimitMillicores
+	}
+	return 0
+}
+
+func (x *MountStatus) GetCgroupDir() string {
+	if x != nil {
+		return x.CgroupDir
+	}
+	return ""
+}
+
+type WatchEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only stream the events of this volume when set.
+	VolumeId      string `protobuf:"bytes,1,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_mountv1_mount_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchEventsRequest) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+type MountEvent struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Type         EventType              `protobuf:"varint,1,opt,name=type,proto3,enum=seaweedfs.mount.v1.EventType" json:"type,omitempty"`
+	VolumeId     string                 `protobuf:"bytes,2,opt,name=volume_id,json=volumeId,proto3" json:"volume_id,omitempty"`
+	TargetPath   string                 `protobuf:"bytes,3,opt,name=target_path,json=targetPath,proto3" json:"target_path,omitempty"`
+	Pid          int32                  `protobuf:"varint,4,opt,name=pid,proto3" json:"pid,omitempty"`
+	Time         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	RestartCount int32                  `protobuf:"varint,6,opt,name=restart_count,json=restartCount,proto3" json:"restart_count,omitempty"`
+	// Set on exits requested by an unmount.
+	Stopped bool `protobuf:"varint,7,opt,name=stopped,proto3" json:"stopped,omitempty"`
+	// Set on exits the supervisor restarts the process after.
+	Restarting bool `protobuf:"varint,8,opt,name=restarting,proto3" json:"restarting,omitempty"`
+	// Set on exits caused by the memory limit of the process.
+	OomKilled     bool `protobuf:"varint,9,opt,name=oom_killed,json=oomKilled,proto3" json:"oom_killed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MountEvent) Reset() {
+	*x = MountEvent{}
+	mi := &file_mountv1_mount_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MountEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MountEvent) ProtoMessage() {}
+
+func (x *MountEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_mountv1_mount_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MountEvent.ProtoReflect.Descriptor instead.
+func (*MountEvent) Descriptor() ([]byte, []int) {
+	return file_mountv1_mount_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MountEvent) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *MountEvent) GetVolumeId() string {
+	if x != nil {
+		return x.VolumeId
+	}
+	return ""
+}
+
+func (x *MountEvent) GetTargetPath() string {
+	if x != nil {
+		return x.TargetPath
+	}
+	return ""
+}
+
+func (x *MountEvent) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *MountEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *MountEvent) GetRestartCount() int32 {
+	if x != nil {
+		return x.RestartCount
+	}
+	return 0
+}
+
+func (x *MountEvent) GetStopped() bool {
+	if x != nil {
+		return x.Stopped
+	}
+	return false
+}
+
+func (x *MountEvent) GetRestarting() bool {
+	if x != nil {
+		return x.Restarting
+	}
+	return false
+}
+
+func (x *MountEvent) GetOomKilled() bool {
+	if x != nil {
+		return x.OomKilled
+	}
+	return false
+}
+
+var File_mountv1_mount_proto protoreflect.FileDescriptor
+
+const file_mountv1_mount_proto_rawDesc = "" +
+	"\n" +
+	"\x13mountv1/mount.proto\x12\x12seaweedfs.mount.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc0\x01\n" +
+	"\x10MountCredentials\x12\x19\n" +
+	"\btls_cert\x18\x01 \x01(\tR\atlsCert\x12\x17\n" +
+	"\atls_key\x18\x02 \x01(\tR\x06tlsKey\x12\x17\n" +
+	"\aca_cert\x18\x03 \x01(\tR\x06caCert\x12*\n" +
+	"\x11filer_signing_key\x18\x04 \x01(\tR\x0ffilerSigningKey\x123\n" +
+	"\x16filer_read_signing_key\x18\x05 \x01(\tR\x13filerReadSigningKey\"\xcd\x02\n" +
+	"\fMountRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12\x1f\n" +
+	"\vtarget_path\x18\x02 \x01(\tR\n" +
+	"targetPath\x12\x1b\n" +
+	"\tcache_dir\x18\x03 \x01(\tR\bcacheDir\x12\x1d\n" +
+	"\n" +
+	"mount_args\x18\x04 \x03(\tR\tmountArgs\x12!\n" +
+	"\flocal_socket\x18\x05 \x01(\tR\vlocalSocket\x12F\n" +
+	"\vcredentials\x18\x06 \x01(\v2$.seaweedfs.mount.v1.MountCredentialsR\vcredentials\x12&\n" +
+	"\x0fmemory_limit_mb\x18\a \x01(\x03R\rmemoryLimitMb\x120\n" +
+	"\x14cpu_limit_millicores\x18\b \x01(\x03R\x12cpuLimitMillicores\"2\n" +
+	"\rMountResponse\x12!\n" +
+	"\flocal_socket\x18\x01 \x01(\tR\vlocalSocket\"-\n" +
+	"\x0eUnmountRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"\x11\n" +
+	"\x0fUnmountResponse\"\r\n" +
+	"\vListRequest\"G\n" +
+	"\fListResponse\x127\n" +
+	"\x06mounts\x18\x01 \x03(\v2\x1f.seaweedfs.mount.v1.MountStatusR\x06mounts\",\n" +
+	"\rStatusRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"\xf3\x03\n" +
+	"\vMountStatus\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\x12\x1f\n" +
+	"\vtarget_path\x18\x02 \x01(\tR\n" +
+	"targetPath\x12\x1b\n" +
+	"\tcache_dir\x18\x03 \x01(\tR\bcacheDir\x12!\n" +
+	"\flocal_socket\x18\x04 \x01(\tR\vlocalSocket\x12\x1d\n" +
+	"\n" +
+	"mount_args\x18\x05 \x03(\tR\tmountArgs\x12\x10\n" +
+	"\x03pid\x18\x06 \x01(\x05R\x03pid\x129\n" +
+	"\n" +
+	"start_time\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tstartTime\x12%\n" +
+	"\x0euptime_seconds\x18\b \x01(\x03R\ruptimeSeconds\x12#\n" +
+	"\rrestart_count\x18\t \x01(\x05R\frestartCount\x12\x16\n" +
+	"\x06exited\x18\n" +
+	" \x01(\bR\x06exited\x12\x1d\n" +
+	"\n" +
+	"oom_killed\x18\v \x01(\bR\toomKilled\x12&\n" +
+	"\x0fmemory_limit_mb\x18\f \x01(\x03R\rmemoryLimitMb\x120\n" +
+	"\x14cpu_limit_millicores\x18\r \x01(\x03R\x12cpuLimitMillicores\x12\x1d\n" +
+	"\n" +
+	"cgroup_dir\x18\x0e \x01(\tR\tcgroupDir\"1\n" +
+	"\x12WatchEventsRequest\x12\x1b\n" +
+	"\tvolume_id\x18\x01 \x01(\tR\bvolumeId\"\xbd\x02\n" +
+	"\n" +
+	"MountEvent\x121\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1d.seaweedfs.mount.v1.EventTypeR\x04type\x12\x1b\n" +
+	"\tvolume_id\x18\x02 \x01(\tR\bvolumeId\x12\x1f\n" +
+	"\vtarget_path\x18\x03 \x01(\tR\n" +
+	"targetPath\x12\x10\n" +
+	"\x03pid\x18\x04 \x01(\x05R\x03pid\x12.\n" +
+	"\x04time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12#\n" +
+	"\rrestart_count\x18\x06 \x01(\x05R\frestartCount\x12\x18\n" +
+	"\astopped\x18\a \x01(\bR\astopped\x12\x1e\n" +
+	"\n" +
+	"restarting\x18\b \x01(\bR\n" +
+	"restarting\x12\x1d\n" +
+	"\n" +
+	"oom_killed\x18\t \x01(\bR\toomKilled*p\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12EVENT_TYPE_STARTED\x10\x01\x12\x15\n" +
+	"\x11EVENT_TYPE_EXITED\x10\x02\x12\x18\n" +
+	"\x14EVENT_TYPE_RESTARTED\x10\x032\xa2\x03\n" +
+	"\fMountService\x12L\n" +
+	"\x05Mount\x12 .seaweedfs.mount.v1.MountRequest\x1a!.seaweedfs.mount.v1.MountResponse\x12R\n" +
+	"\aUnmount\x12\".seaweedfs.mount.v1.UnmountRequest\x1a#.seaweedfs.mount.v1.UnmountResponse\x12I\n" +
+	"\x04List\x12\x1f.seaweedfs.mount.v1.ListRequest\x1a .seaweedfs.mount.v1.ListResponse\x12L\n" +
+	"\x06Status\x12!.seaweedfs.mount.v1.StatusRequest\x1a\x1f.seaweedfs.mount.v1.MountStatus\x12W\n" +
+	"\vWatchEvents\x12&.seaweedfs.mount.v1.WatchEventsRequest\x1a\x1e.seaweedfs.mount.v1.MountEvent0\x01BDZBgithub.com/seaweedfs/seaweedfs-csi-driver/pkg/mountmanager/mountv1b\x06proto3"
+
+var (
+	file_mountv1_mount_proto_rawDescOnce sync.Once
+	file_mountv1_mount_proto_rawDescData []byte
+)
+
+func file_mountv1_mount_proto_rawDescGZIP() []byte {
+	file_mountv1_mount_proto_rawDescOnce.Do(func() {
+		file_mountv1_mount_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_mountv1_mount_proto_rawDesc), len(file_mountv1_mount_proto_rawDesc)))
+	})
+	return file_mountv1_mount_proto_rawDescData
+}
+
+var file_mountv1_mount_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_mountv1_mount_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_mountv1_mount_proto_goTypes = []any{
+	(EventType)(0),                // 0: seaweedfs.mount.v1.EventType
+	(*MountCredentials)(nil),      // 1: seaweedfs.mount.v1.MountCredentials
+	(*MountRequest)(nil),          // 2: seaweedfs.mount.v1.MountRequest
+	(*MountResponse)(nil),         // 3: seaweedfs.mount.v1.MountResponse
+	(*UnmountRequest)(nil),        // 4: seaweedfs.mount.v1.UnmountRequest
+	(*UnmountResponse)(nil),       // 5: seaweedfs.mount.v1.UnmountResponse
+	(*ListRequest)(nil),           // 6: seaweedfs.mount.v1.ListRequest
+	(*ListResponse)(nil),          // 7: seaweedfs.mount.v1.ListResponse
+	(*StatusRequest)(nil),         // 8: seaweedfs.mount.v1.StatusRequest
+	(*MountStatus)(nil),           // 9: seaweedfs.mount.v1.MountStatus
+	(*WatchEventsRequest)(nil),    // 10: seaweedfs.mount.v1.WatchEventsRequest
+	(*MountEvent)(nil),            // 11: seaweedfs.mount.v1.MountEvent
+	(*timestamppb.Timestamp)(nil), // 12: google.protobuf.Timestamp
+}
+var file_mountv1_mount_proto_depIdxs = []int32{
+	1,  // 0: seaweedfs.mount.v1.MountRequest.credentials:type_name -> seaweedfs.mount.v1.MountCredentials
+	9,  // 1: seaweedfs.mount.v1.ListResponse.mounts:type_name -> seaweedfs.mount.v1.MountStatus
+	12, // 2: seaweedfs.mount.v1.MountStatus.start_time:type_name -> google.protobuf.Timestamp
+	0,  // 3: seaweedfs.mount.v1.MountEvent.type:type_name -> seaweedfs.mount.v1.EventType
+	12, // 4: seaweedfs.mount.v1.MountEvent.time:type_name -> google.protobuf.Timestamp
+	2,  // 5: seaweedfs.mount.v1.MountService.Mount:input_type -> seaweedfs.mount.v1.MountRequest
+	4,  // 6: seaweedfs.mount.v1.MountService.Unmount:input_type -> seaweedfs.mount.v1.UnmountRequest
+	6,  // 7: seaweedfs.mount.v1.MountService.List:input_type -> seaweedfs.mount.v1.ListRequest
+	8,  // 8: seaweedfs.mount.v1.MountService.Status:input_type -> seaweedfs.mount.v1.StatusRequest
+	10, // 9: seaweedfs.mount.v1.MountService.WatchEvents:input_type -> seaweedfs.mount.v1.WatchEventsRequest
+	3,  // 10: seaweedfs.mount.v1.MountService.Mount:output_type -> seaweedfs.mount.v1.MountResponse
+	5,  // 11: seaweedfs.mount.v1.MountService.Unmount:output_type -> seaweedfs.mount.v1.UnmountResponse
+	7,  // 12: seaweedfs.mount.v1.MountService.List:output_type -> seaweedfs.mount.v1.ListResponse
+	9,  // 13: seaweedfs.mount.v1.MountService.Status:output_type -> seaweedfs.mount.v1.MountStatus
+	11, // 14: seaweedfs.mount.v1.MountService.WatchEvents:output_type -> seaweedfs.mount.v1.MountEvent
+	10, // [10:15] is the sub-list for method output_type
+	5,  // [5:10] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_mountv1_mount_proto_init() }
+func file_mountv1_mount_proto_init() {
+	if File_mountv1_mount_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mountv1_mount_proto_rawDesc), len(file_mountv1_mount_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mountv1_mount_proto_goTypes,
+		DependencyIndexes: file_mountv1_mount_proto_depIdxs,
+		EnumInfos:         file_mountv1_mount_proto_enumTypes,
+		MessageInfos:      file_mountv1_mount_proto_msgTypes,
+	}.Build()
+	File_mountv1_mount_proto = out.File
+	file_mountv1_mount_proto_goTypes = nil
+	file_mountv1_mount_proto_depIdxs = nil
+}
@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: mountv1/mount.proto
+
+package mountv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MountService_Mount_FullMethodName       = "/seaweedfs.mount.v1.MountService/Mount"
+	MountService_Unmount_FullMethodName     = "/seaweedfs.mount.v1.MountService/Unmount"
+	MountService_List_FullMethodName        = "/seaweedfs.mount.v1.MountService/List"
+	MountService_Status_FullMethodName      = "/seaweedfs.mount.v1.MountService/Status"
+	MountService_WatchEvents_FullMethodName = "/seaweedfs.mount.v1.MountService/WatchEvents"
+)
+
+// MountServiceClient is the client API for MountService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MountService is the API of the mount service to the node plugin, served
+// on the unix socket of the mount service next to its JSON API.
+type MountServiceClient interface {
+	// Mount starts a weed mount process for a volume.
+	Mount(ctx context.Context, in *MountRequest, opts ...grpc.CallOption) (*MountResponse, error)
+	// Unmount stops the weed mount process of a volume.
+	Unmount(ctx context.Context, in *UnmountRequest, opts ...grpc.CallOption) (*UnmountResponse, error)
+	// List returns all mounts.
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+	// Status returns the mount of a volume, or NOT_FOUND.
+	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*MountStatus, error)
+	// WatchEvents streams the starts, exits and restarts of weed mount
+	// processes as they happen.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MountEvent], error)
+}
+
+type mountServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMountServiceClient(cc grpc.ClientConnInterface) MountServiceClient {
+	return &mountServiceClient{cc}
+}
+
+func (c *mountServiceClient) Mount(ctx context.Context, in *MountRequest, opts ...grpc.CallOption) (*MountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MountResponse)
+	err := c.cc.Invoke(ctx, MountService_Mount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mountServiceClient) Unmount(ctx context.Context, in *UnmountRequest, opts ...grpc.CallOption) (*UnmountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnmountResponse)
+	err := c.cc.Invoke(ctx, MountService_Unmount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mountServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, MountService_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mountServiceClient) Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*MountStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MountStatus)
+	err := c.cc.Invoke(ctx, MountService_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *mountServiceClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MountEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MountService_ServiceDesc.Streams[0], MountService_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, MountEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MountService_WatchEventsClient = grpc.ServerStreamingClient[MountEvent]
+
+// MountServiceServer is the server API for MountService service.
+// All implementations must embed UnimplementedMountServiceServer
+// for forward compatibility.
+//
+// MountService is the API of the mount service to the node plugin, served
+// on the unix socket of the mount service next to its JSON API.
+type MountServiceServer interface {
+	// Mount starts a weed mount process for a volume.
+	Mount(context.Context, *MountRequest) (*MountResponse, error)
+	// Unmount stops the weed mount process of a volume.
+	Unmount(context.Context, *UnmountRequest) (*UnmountResponse, error)
+	// List returns all mounts.
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	// Status returns the mount of a volume, or NOT_FOUND.
+	Status(context.Context, *StatusRequest) (*MountStatus, error)
+	// WatchEvents streams the starts, exits and restarts of weed mount
+	// processes as they happen.
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[MountEvent]) error
+	mustEmbedUnimplementedMountServiceServer()
+}
+
+// UnimplementedMountServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMountServiceServer struct{}
+
+func (UnimplementedMountServiceServer) Mount(context.Context, *MountRequest) (*MountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Mount not implemented")
+}
+func (UnimplementedMountServiceServer) Unmount(context.Context, *UnmountRequest) (*UnmountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unmount not implemented")
+}
+func (UnimplementedMountServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedMountServiceServer) Status(context.Context, *StatusRequest) (*MountStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedMountServiceServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[MountEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedMountServiceServer) mustEmbedUnimplementedMountServiceServer() {}
+func (UnimplementedMountServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeMountServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MountServiceServer will
+// result in compilation errors.
+type UnsafeMountServiceServer interface {
+	mustEmbedUnimplementedMountServiceServer()
+}
+
+func RegisterMountServiceServer(s grpc.ServiceRegistrar, srv MountServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMountServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MountService_ServiceDesc, srv)
+}
+
+func _MountService_Mount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MountServiceServer).Mount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MountService_Mount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MountServiceServer).Mount(ctx, req.(*MountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MountService_Unmount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnmountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MountServiceServer).Unmount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MountService_Unmount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MountServiceServer).Unmount(ctx, req.(*UnmountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MountService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MountServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MountService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MountServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MountService_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MountServiceServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MountService_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MountServiceServer).Status(ctx, req.(*StatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MountService_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MountServiceServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, MountEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MountService_WatchEventsServer = grpc.ServerStreamingServer[MountEvent]
+
+// MountService_ServiceDesc is the grpc.ServiceDesc for MountService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MountService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "seaweedfs.mount.v1.MountService",
+	HandlerType: (*MountServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Mount",
+			Handler:    _MountService_Mount_Handler,
+		},
+		{
+			MethodName: "Unmount",
+			Handler:    _MountService_Unmount_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _MountService_List_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _MountService_Status_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _MountService_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "mountv1/mount.proto",
+}
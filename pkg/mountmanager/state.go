@@ -0,0 +1,253 @@
+package mountmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+)
+
+// mountLogName is the file below the cache directory of a mount the output
+// of its weed mount process is written to.
+const mountLogName = "weed-mount.log"
+
+func mountLogPath(cacheDir string) string {
+	return filepath.Join(cacheDir, mountLogName)
+}
+
+// mountState is the journaled form of a mountEntry. Credentials are not part
+// of it: they only live in the cache directory of the mount.
+type mountState struct {
+	VolumeID    string   `json:"volumeId"`
+	TargetPath  string   `json:"targetPath"`
+	CacheDir    string   `json:"cacheDir"`
+	LocalSocket string   `json:"localSocket"`
+	Pid         int      `json:"pid"`
+	Args        []string `json:"args"`
+	// StartTime and RestartCount carry the status of the mount over
+	StartTime    time.Time `json:"startTime"`
+	RestartCount int       `json:"restartCount,omitempty"`
+	// the resource limits of the mount and the cgroup enforcing them
+	MemoryLimitMB      int64  `json:"memoryLimitMB,omitempty"`
+	CPULimitMillicores int64  `json:"cpuLimitMillicores,omitempty"`
+	CgroupDir          string `json:"cgroupDir,omitempty"`
+}
+
+// saveStateLocked writes the current mounts to the state file. The caller
+// holds m.mu. Failures are logged: the mounts keep working, only a restarted
+// manager would not find them.
+func (m *Manager) saveStateLocked() {
+	if m.stateFile == "" {
+		return
+	}
+	states := make([]mountState, 0, len(m.mounts))
+	for _, entry := range m.mounts {
+		states = append(states, mountState{
+			VolumeID:     entry.volumeID,
+			TargetPath:   entry.targetPath,
+			CacheDir:     entry.cacheDir,
+			LocalSocket:  entry.localSocket,
+			Pid:          entry.process.process.Pid,
+			Args:         entry.args,
+			StartTime:    entry.startTime,
+			RestartCount: entry.restartCount,
+
+			MemoryLimitMB:      entry.memoryLimitMB,
+			CPULimitMillicores: entry.cpuLimitMillicores,
+			CgroupDir:          entry.process.cgroupDir,
+		})
+	}
+	slices.SortFunc(states, func(a, b mountState) int { return strings.Compare(a.VolumeID, b.VolumeID) })
+	if err := writeStateFile(m.stateFile, states); err != nil {
+		glog.Warningf("failed to save mount state to %s: %v", m.stateFile, err)
+	}
+}
+
+// writeStateFile replaces the state file atomically, so that a crash never
+// leaves a truncated one behind.
+func writeStateFile(stateFile string, states []mountState) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		return err
+	}
+	tmp := stateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, stateFile)
+}
+
+func readStateFile(stateFile string) ([]mountState, error) {
+	data, err := os.ReadFile(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var states []mountState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", stateFile, err)
+	}
+	return states, nil
+}
+
+// restoreState re-adopts the weed mount processes journaled by a previous
+// manager that are still serving their mounts, and cleans up after the
+// others.
+func (m *Manager) restoreState() {
+	states, err := readStateFile(m.stateFile)
+	if err != nil {
+		glog.Warningf("not restoring mounts: %v", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, state := range states {
+		entry, err := adoptMount(state)
+		if err != nil {
+			glog.Warningf("volume %s: not adopting weed mount process %d: %v", state.VolumeID, state.Pid, err)
+			cleanupMount(state)
+			continue
+		}
+		m.mounts[state.VolumeID] = entry
+		go m.watchProcessExit(state.VolumeID, entry)
+		glog.Infof("volume %s: adopted weed mount process %d at %s", state.VolumeID, state.Pid, state.TargetPath)
+	}
+	m.saveStateLocked()
+}
+
+// adoptMount takes over the weed mount process of state if it is still the
+// process that was started for the mount and the target is mounted.
+func adoptMount(state mountState) (*mountEntry, error) {
+	process, err := findMountProcess(state)
+	if err != nil {
+		return nil, err
+	}
+	notMnt, err := kubeMounter.IsLikelyNotMountPoint(state.TargetPath)
+	if err != nil {
+		return nil, fmt.Errorf("checking mount point %s: %w", state.TargetPath, err)
+	}
+	if notMnt {
+		return nil, fmt.Errorf("%s is not mounted", state.TargetPath)
+	}
+
+	p := &weedMountProcess{
+		process:   process,
+		target:    state.TargetPath,
+		cgroupDir: state.CgroupDir,
+		exited:    make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go p.wait(func() error { return pollExit(process) })
+
+	logPath := mountLogPath(state.CacheDir)
+	var offset int64
+	if info, err := os.Stat(logPath); err == nil {
+		offset = info.Size()
+	}
+	go followLogs(logPath, offset, state.VolumeID, p.done)
+
+	return &mountEntry{
+		volumeID:     state.VolumeID,
+		targetPath:   state.TargetPath,
+		cacheDir:     state.CacheDir,
+		localSocket:  state.LocalSocket,
+		args:         state.Args,
+		startTime:    state.StartTime,
+		restartCount: state.RestartCount,
+		process:      p,
+
+		memoryLimitMB:      state.MemoryLimitMB,
+		cpuLimitMillicores: state.CPULimitMillicores,
+	}, nil
+}
+
+// findMountProcess returns the process of state, verifying through its
+// command line that the pid has not been reused by another process.
+func findMountProcess(state mountState) (*os.Process, error) {
+	if state.Pid <= 0 {
+		return nil, fmt.Errorf("invalid pid %d", state.Pid)
+	}
+	if !processRunning(state.Pid) {
+		return nil, errors.New("process is gone")
+	}
+	cmdline, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(state.Pid), "cmdline"))
+	if err != nil {
+		return nil, fmt.Errorf("reading command line: %w", err)
+	}
+	argv := strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")
+	if len(argv) == 0 || !slices.Equal(argv[1:], state.Args) {
+		return nil, fmt.Errorf("pid %d now runs %q", state.Pid, argv)
+	}
+	return os.FindProcess(state.Pid)
+}
+
+// processRunning reports whether the process pid exists and has not exited.
+// Exited processes linger as zombies until their parent reaps them, which
+// a previous manager that started them no longer does.
+func processRunning(pid int) bool {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	// the state follows the command name, which is in parentheses and may
+	// contain anything
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 || i+2 >= len(stat) {
+		return false
+	}
+	return stat[i+2] != 'Z' && stat[i+2] != 'X'
+}
+
+// pollExit waits for an adopted process to exit. It returns nil as the exit
+// status of a process that is not a child of this one is unknown.
+func pollExit(process *os.Process) error {
+	for processRunning(process.Pid) {
+		time.Sleep(time.Second)
+	}
+	// Reaps the process if it was reparented to this one, as happens when
+	// the manager runs as init of its container
+	_, _ = process.Wait()
+	return nil
+}
+
+// cleanupMount releases what is left of a mount that cannot be adopted: its
+// weed mount process if it still runs, the target and the cache directory.
+func cleanupMount(state mountState) {
+	if process, err := findMountProcess(state); err == nil {
+		glog.Infof("volume %s: stopping weed mount process %d", state.VolumeID, state.Pid)
+		p := &weedMountProcess{
+			process:   process,
+			target:    state.TargetPath,
+			cgroupDir: state.CgroupDir,
+			exited:    make(chan struct{}),
+			done:      make(chan struct{}),
+		}
+		go p.wait(func() error { return pollExit(process) })
+		if err := p.stop(); err != nil {
+			glog.Warningf("volume %s: %v", state.VolumeID, err)
+		}
+	} else {
+		if err := kubeMounter.Unmount(state.TargetPath); err == nil {
+			glog.Infof("volume %s: unmounted stale target %s", state.VolumeID, state.TargetPath)
+		}
+		if state.CgroupDir != "" {
+			removeMountCgroup(state.CgroupDir)
+		}
+	}
+	if err := os.RemoveAll(state.CacheDir); err != nil {
+		glog.Warningf("volume %s: failed to remove cache dir %s: %v", state.VolumeID, state.CacheDir, err)
+	}
+}
@@ -0,0 +1,118 @@
+package mountmanager
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateFileRoundTrip(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state", "mounts.json")
+	states := []mountState{{
+		VolumeID:    "vol-1",
+		TargetPath:  "/staging/vol-1",
+		CacheDir:    "/cache/vol-1",
+		LocalSocket: "/sockets/vol-1.sock",
+		Pid:         42,
+		Args:        []string{"mount", "-dir=/staging/vol-1"},
+	}}
+	if err := writeStateFile(stateFile, states); err != nil {
+		t.Fatalf("writeStateFile: %v", err)
+	}
+	info, err := os.Stat(stateFile)
+	if err != nil {
+		t.Fatalf("stat state file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("state file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	got, err := readStateFile(stateFile)
+	if err != nil {
+		t.Fatalf("readStateFile: %v", err)
+	}
+	if len(got) != 1 || got[0].VolumeID != "vol-1" || got[0].Pid != 42 || len(got[0].Args) != 2 {
+		t.Fatalf("readStateFile = %+v", got)
+	}
+
+	if got, err := readStateFile(filepath.Join(t.TempDir(), "missing.json")); err != nil || got != nil {
+		t.Fatalf("readStateFile of a missing file = %v, %v, want no state", got, err)
+	}
+}
+
+func TestFindMountProcessVerifiesCommandLine(t *testing.T) {
+	if _, err := os.Stat("/proc/self/cmdline"); err != nil {
+		t.Skip("no /proc")
+	}
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	if _, err := findMountProcess(mountState{Pid: cmd.Process.Pid, Args: []string{"30"}}); err != nil {
+		t.Fatalf("findMountProcess of the started process: %v", err)
+	}
+	if _, err := findMountProcess(mountState{Pid: cmd.Process.Pid, Args: []string{"mount", "-dir=/x"}}); err == nil {
+		t.Fatal("expected a pid running other arguments to be rejected")
+	}
+}
+
+// TestRestoreStateCleansUpUnmountedProcess verifies that a journaled mount
+// whose process still runs but whose target is no longer mounted is not
+// adopted: the process is stopped, its cache removed and the state file
+// rewritten without it.
+func TestRestoreStateCleansUpUnmountedProcess(t *testing.T) {
+	if _, err := os.Stat("/proc/self/cmdline"); err != nil {
+		t.Skip("no /proc")
+	}
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	defer func() { _ = cmd.Process.Kill() }()
+
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stateFile := filepath.Join(dir, "mounts.json")
+	if err := writeStateFile(stateFile, []mountState{{
+		VolumeID:   "vol-1",
+		TargetPath: filepath.Join(dir, "target"),
+		CacheDir:   cacheDir,
+		Pid:        cmd.Process.Pid,
+		Args:       []string{"30"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(Config{StateFile: stateFile})
+
+	if m.getMount("vol-1") != nil {
+		t.Fatal("expected the unmounted volume not to be adopted")
+	}
+	select {
+	case <-exited:
+	case <-time.After(10 * time.Second):
+		t.Fatal("process of the unadoptable mount was not stopped")
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Errorf("expected cache dir removed, got %v", err)
+	}
+	states, err := readStateFile(stateFile)
+	if err != nil || len(states) != 0 {
+		t.Errorf("state after restore = %+v, %v, want empty", states, err)
+	}
+}
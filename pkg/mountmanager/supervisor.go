@@ -0,0 +1,134 @@
+package mountmanager
+
+import (
+	"time"
+
+	"github.com/seaweedfs/seaweedfs/weed/glog"
+)
+
+const (
+	// DefaultMaxRestarts is the crash-loop limit of supervised mounts: how
+	// many times in a row a weed mount process that keeps crashing shortly
+	// after its start is restarted before the mount is given up.
+	DefaultMaxRestarts = 5
+
+	restartInitialBackoff = time.Second
+	restartMaxBackoff     = time.Minute
+	// restartStableAfter is how long a process has to run for its crash to
+	// no longer count toward the crash-loop limit
+	restartStableAfter = time.Minute
+)
+
+// restartBackoff returns how long to wait before the restart following
+// crashes consecutive quick crashes.
+func restartBackoff(crashes int) time.Duration {
+	backoff := restartInitialBackoff
+	for i := 1; i < crashes && backoff < restartMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, restartMaxBackoff)
+}
+
+// superviseExit decides what happens to entry after its weed mount process
+// exited on its own: with supervision the entry stays in place, marked as
+// exited, and a restart is scheduled. It returns false if the entry is to
+// be removed instead. The caller holds m.mu.
+func (m *Manager) superviseExit(volumeID string, entry *mountEntry, now time.Time) bool {
+	if !m.restartCrashed || entry.process.stopRequested() {
+		return false
+	}
+	crashes, ok := m.nextRestart(entry, now)
+	if !ok {
+		glog.Errorf("volume %s: weed mount crashed %d times in a row, giving up (target: %s)", volumeID, crashes, entry.targetPath)
+		return false
+	}
+	backoff := restartBackoff(crashes)
+	reason := "exited"
+	if entry.process.oomKilled.Load() {
+		reason = "was OOM-killed"
+	}
+	glog.Warningf("volume %s: weed mount process %s, restarting in %v (attempt %d of %d)", volumeID, reason, backoff, crashes, m.maxRestarts)
+	go m.restartAfter(volumeID, entry, crashes, backoff)
+	return true
+}
+
+// nextRestart counts the crash of the process of entry at now among the
+// quick crashes in a row, and reports whether it is within the crash-loop
+// limit.
+func (m *Manager) nextRestart(entry *mountEntry, now time.Time) (int, bool) {
+	crashes := entry.consecutiveCrashes + 1
+	if now.Sub(entry.startTime) >= restartStableAfter {
+		crashes = 1
+	}
+	return crashes, crashes <= m.maxRestarts
+}
+
+// restartAfter starts a new weed mount process for entry with the same
+// arguments once backoff has passed, unless the volume has been unmounted
+// or mounted again in the meantime.
+func (m *Manager) restartAfter(volumeID string, entry *mountEntry, crashes int, backoff time.Duration) {
+	time.Sleep(backoff)
+
+	lock := m.locks.get(volumeID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if m.getMount(volumeID) != entry {
+		glog.Infof("volume %s: not restarting weed mount, the mount has changed", volumeID)
+		return
+	}
+
+	restarted := &mountEntry{
+		volumeID:           entry.volumeID,
+		targetPath:         entry.targetPath,
+		cacheDir:           entry.cacheDir,
+		localSocket:        entry.localSocket,
+		args:               entry.args,
+		memoryLimitMB:      entry.memoryLimitMB,
+		cpuLimitMillicores: entry.cpuLimitMillicores,
+		startTime:          time.Now(),
+		restartCount:       entry.restartCount + 1,
+		consecutiveCrashes: crashes,
+	}
+	process, err := m.restartProcess(restarted)
+	if err != nil {
+		glog.Errorf("volume %s: restarting weed mount failed: %v", volumeID, err)
+		// a failed start counts as a crash of the restarted process
+		restarted.process = entry.process
+	} else {
+		restarted.process = process
+	}
+
+	if err != nil {
+		m.mu.Lock()
+		supervised := m.superviseExit(volumeID, restarted, restarted.startTime)
+		if supervised {
+			m.mounts[volumeID] = restarted
+		} else {
+			delete(m.mounts, volumeID)
+		}
+		m.saveStateLocked()
+		m.mu.Unlock()
+		if !supervised {
+			// the mount is given up, nobody will unmount it
+			removeCacheDir(volumeID, restarted.cacheDir)
+		}
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mounts[volumeID] = restarted
+	m.saveStateLocked()
+	m.publish(restarted.event(EventRestarted, restarted.startTime))
+
+	go m.watchProcessExit(volumeID, restarted)
+	glog.Infof("volume %s: restarted weed mount process at %s (restart %d)", volumeID, restarted.targetPath, restarted.restartCount)
+}
+
+func (m *Manager) restartProcess(entry *mountEntry) (*weedMountProcess, error) {
+	if err := ensureTargetClean(entry.targetPath); err != nil {
+		return nil, err
+	}
+	return m.startProcess(entry.volumeID, entry.args, entry.targetPath, entry.cacheDir, entry.memoryLimitMB, entry.cpuLimitMillicores)
+}
@@ -0,0 +1,95 @@
+package mountmanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		3:  4 * time.Second,
+		10: time.Minute,
+	}
+	for crashes, want := range tests {
+		if got := restartBackoff(crashes); got != want {
+			t.Errorf("restartBackoff(%d) = %v, want %v", crashes, got, want)
+		}
+	}
+}
+
+func TestNextRestart(t *testing.T) {
+	m := NewManager(Config{RestartCrashed: true, MaxRestarts: 3})
+	now := time.Now()
+
+	quick := &mountEntry{startTime: now.Add(-time.Second), consecutiveCrashes: 1}
+	if crashes, ok := m.nextRestart(quick, now); crashes != 2 || !ok {
+		t.Errorf("quick crash = %d, %v, want 2, true", crashes, ok)
+	}
+
+	stable := &mountEntry{startTime: now.Add(-time.Hour), consecutiveCrashes: 3}
+	if crashes, ok := m.nextRestart(stable, now); crashes != 1 || !ok {
+		t.Errorf("crash after a stable run = %d, %v, want 1, true", crashes, ok)
+	}
+
+	looping := &mountEntry{startTime: now.Add(-time.Second), consecutiveCrashes: 3}
+	if crashes, ok := m.nextRestart(looping, now); crashes != 4 || ok {
+		t.Errorf("crash loop = %d, %v, want 4, false", crashes, ok)
+	}
+}
+
+// TestSuperviseExitSkipsRequestedStops verifies that the supervisor
+// leaves processes stopped by Unmount, and every process when
+// supervision is disabled, to watchProcessExit's removal.
+func TestSuperviseExitSkipsRequestedStops(t *testing.T) {
+	now := time.Now()
+	newEntry := func() *mountEntry {
+		return &mountEntry{
+			startTime: now,
+			process:   &weedMountProcess{exited: make(chan struct{}), done: make(chan struct{})},
+		}
+	}
+
+	if NewManager(Config{}).superviseExit("vol-1", newEntry(), now) {
+		t.Error("expected no restart without supervision")
+	}
+
+	stopped := newEntry()
+	stopped.process.stopping.Store(true)
+	if NewManager(Config{RestartCrashed: true}).superviseExit("vol-1", stopped, now) {
+		t.Error("expected no restart of a process asked to stop")
+	}
+}
+
+// TestRestartAfterGivingUpRemovesCacheDir verifies that a mount given up
+// after a failed restart does not leave its cache and credentials behind.
+func TestRestartAfterGivingUpRemovesCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	if _, err := writeCredentials(cacheDir, &MountCredentials{FilerSigningKey: "secret"}); err != nil {
+		t.Fatalf("writeCredentials: %v", err)
+	}
+
+	m := NewManager(Config{WeedBinary: filepath.Join(dir, "missing-weed"), RestartCrashed: true, MaxRestarts: 1})
+	entry := &mountEntry{
+		volumeID:   "vol-1",
+		targetPath: filepath.Join(dir, "target"),
+		cacheDir:   cacheDir,
+		args:       []string{"mount"},
+		startTime:  time.Now(),
+		process:    &weedMountProcess{exited: make(chan struct{}), done: make(chan struct{})},
+	}
+	m.mounts["vol-1"] = entry
+
+	m.restartAfter("vol-1", entry, 1, 0)
+
+	if m.getMount("vol-1") != nil {
+		t.Fatal("given up mount is still tracked")
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Fatalf("cache dir of the given up mount still exists: %v", err)
+	}
+}
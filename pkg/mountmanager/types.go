@@ -1,5 +1,10 @@
 package mountmanager
 
+import (
+	"errors"
+	"time"
+)
+
 // MountRequest contains all information needed to start a weed mount process.
 type MountRequest struct {
 	VolumeID    string   `json:"volumeId"`
@@ -7,6 +12,25 @@ type MountRequest struct {
 	CacheDir    string   `json:"cacheDir"`
 	MountArgs   []string `json:"mountArgs"`
 	LocalSocket string   `json:"localSocket"`
+	// Credentials override the security.toml of the node for this mount
+	Credentials *MountCredentials `json:"credentials,omitempty"`
+	// MemoryLimitMB and CPULimitMillicores limit the resources of the weed
+	// mount process when the mount service runs it in a cgroup, 0 meaning
+	// unlimited
+	MemoryLimitMB      int64 `json:"memoryLimitMB,omitempty"`
+	CPULimitMillicores int64 `json:"cpuLimitMillicores,omitempty"`
+}
+
+// MountCredentials are the filer credentials of a volume, taken from the
+// CSI secrets of the volume. PEM encoded certificates and keys.
+type MountCredentials struct {
+	TLSCert string `json:"tlsCert,omitempty"`
+	TLSKey  string `json:"tlsKey,omitempty"`
+	CACert  string `json:"caCert,omitempty"`
+	// FilerSigningKey and FilerReadSigningKey are the jwt.filer_signing
+	// and jwt.filer_signing.read keys
+	FilerSigningKey     string `json:"filerSigningKey,omitempty"`
+	FilerReadSigningKey string `json:"filerReadSigningKey,omitempty"`
 }
 
 // MountResponse is returned after a successful mount request.
@@ -22,6 +46,70 @@ type UnmountRequest struct {
 // UnmountResponse is the response of a successful unmount request.
 type UnmountResponse struct{}
 
+// MountStatus describes a mount of the mount service and its weed mount
+// process.
+type MountStatus struct {
+	VolumeID    string   `json:"volumeId"`
+	TargetPath  string   `json:"targetPath"`
+	CacheDir    string   `json:"cacheDir"`
+	LocalSocket string   `json:"localSocket"`
+	MountArgs   []string `json:"mountArgs"`
+	Pid         int      `json:"pid"`
+	// StartTime is when the current weed mount process was started
+	StartTime     time.Time `json:"startTime"`
+	UptimeSeconds int64     `json:"uptimeSeconds"`
+	// RestartCount is how often the weed mount process of the mount has
+	// been restarted
+	RestartCount int `json:"restartCount"`
+	// Exited is set once the weed mount process has exited
+	Exited bool `json:"exited"`
+	// OOMKilled is set when the kernel killed the weed mount process for
+	// exceeding its memory limit
+	OOMKilled          bool   `json:"oomKilled,omitempty"`
+	MemoryLimitMB      int64  `json:"memoryLimitMB,omitempty"`
+	CPULimitMillicores int64  `json:"cpuLimitMillicores,omitempty"`
+	CgroupDir          string `json:"cgroupDir,omitempty"`
+}
+
+// EventType is the kind of a MountEvent.
+type EventType string
+
+const (
+	// EventStarted is sent when a weed mount process is started for a new
+	// mount.
+	EventStarted EventType = "started"
+	// EventExited is sent when a weed mount process exits.
+	EventExited EventType = "exited"
+	// EventRestarted is sent when the supervisor restarted a crashed weed
+	// mount process.
+	EventRestarted EventType = "restarted"
+)
+
+// MountEvent reports a change of the weed mount process of a mount.
+type MountEvent struct {
+	Type         EventType `json:"type"`
+	VolumeID     string    `json:"volumeId"`
+	TargetPath   string    `json:"targetPath"`
+	Pid          int       `json:"pid"`
+	Time         time.Time `json:"time"`
+	RestartCount int       `json:"restartCount"`
+	// Stopped is set on exits requested by an unmount
+	Stopped bool `json:"stopped,omitempty"`
+	// Restarting is set on exits the supervisor restarts the process after
+	Restarting bool `json:"restarting,omitempty"`
+	// OOMKilled is set on exits caused by the memory limit of the process
+	OOMKilled bool `json:"oomKilled,omitempty"`
+}
+
+// ListMountsResponse is the response of a request for all mounts.
+type ListMountsResponse struct {
+	Mounts []MountStatus `json:"mounts"`
+}
+
+// ErrVolumeNotMounted is returned for status requests of volumes the mount
+// service does not have a mount of.
+var ErrVolumeNotMounted = errors.New("volume not mounted")
+
 // ErrorResponse is returned when the mount service encounters a failure.
 type ErrorResponse struct {
 	Error string `json:"error"`
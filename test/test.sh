@@ -19,7 +19,7 @@ make build
     --ginkgo.v\
     --csi.testvolumeparameters="$(pwd)/test/sanity/params.yaml"\
     --csi.endpoint="$endpoint"\    
-    --ginkgo.skip="should not fail when requesting to create a volume with already existing name and same capacity|should fail when requesting to create a volume with already existing name and different capacity|should work|should fail when the requested volume does not exist|should return appropriate capabilities"
+    --ginkgo.skip="should work|should fail when the requested volume does not exist|should return appropriate capabilities"
 
 ../csi-test/cmd/csi-sanity/csi-test\
     --ginkgo.v\